@@ -0,0 +1,234 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/ushu/udemy-backup/client"
+	"golang.org/x/net/html"
+)
+
+// name of the directory receiving the images of an article, in the lecture assets directory
+const articleImagesDir = "images"
+
+// elements kept in the articles (the others are replaced by their contents)
+var articleElements = setOf(
+	"a", "abbr", "b", "blockquote", "br", "caption", "cite", "code", "col", "colgroup",
+	"dd", "del", "details", "dfn", "div", "dl", "dt", "em", "figcaption", "figure",
+	"h1", "h2", "h3", "h4", "h5", "h6", "hr", "i", "img", "ins", "kbd", "li", "mark",
+	"ol", "p", "pre", "q", "s", "samp", "small", "span", "strike", "strong", "sub",
+	"summary", "sup", "table", "tbody", "td", "tfoot", "th", "thead", "tr", "u", "ul", "var",
+)
+
+// elements removed from the articles, along with their contents
+var unsafeElements = setOf(
+	"script", "style", "iframe", "frame", "frameset", "object", "embed", "applet", "form",
+	"input", "button", "select", "textarea", "link", "meta", "base", "noscript", "noembed",
+	"noframes", "template", "title",
+)
+
+// attributes kept in the articles, for all the elements or by element
+var (
+	articleAttributes        = setOf("title", "lang", "dir", "class")
+	articleElementAttributes = map[string]map[string]bool{
+		"a":          setOf("href", "name"),
+		"img":        setOf("src", "alt", "width", "height"),
+		"blockquote": setOf("cite"),
+		"q":          setOf("cite"),
+		"del":        setOf("cite"),
+		"ins":        setOf("cite"),
+		"ol":         setOf("start", "type"),
+		"li":         setOf("value"),
+		"td":         setOf("colspan", "rowspan"),
+		"th":         setOf("colspan", "rowspan", "scope"),
+		"col":        setOf("span"),
+		"colgroup":   setOf("span"),
+	}
+	urlAttributes = setOf("href", "src", "cite")
+)
+
+func setOf(values ...string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+var articleTemplate = template.Must(template.New("article").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+body { max-width: 48em; margin: 2em auto; padding: 0 1em; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.6; color: #29303b; }
+img { max-width: 100%; }
+pre { padding: 1em; overflow: auto; background: #f7f8fa; }
+code { font-family: Menlo, Consolas, monospace; font-size: 0.9em; }
+blockquote { margin-left: 0; padding-left: 1em; border-left: 4px solid #dedfe0; color: #686f7a; }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+{{ .Body }}
+</body>
+</html>
+`))
+
+// listArticleAssets returns the HTML and Markdown exports of an article lecture, along
+// with the images it embeds (to be downloaded into the lecture assets directory)
+func listArticleAssets(lecture *client.Lecture, chapDir, prefix string) ([]Asset, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(lecture.Asset.Body))
+	if err != nil {
+		return nil, err
+	}
+	body := doc.Find("body")
+	sanitizeArticle(body)
+
+	// images are downloaded locally, and the links rewritten to the local copies
+	var assets []Asset
+	seen := make(map[string]bool)
+	body.Find("img[src]").Each(func(_ int, img *goquery.Selection) {
+		src, _ := img.Attr("src")
+		u, err := url.Parse(src)
+		if err != nil || u.Scheme == "data" {
+			return
+		}
+		if u.Scheme == "" {
+			u.Scheme = "https" // <- protocol-relative URLs
+		}
+		if u.Host == "" {
+			return
+		}
+		name := articleImageName(u)
+		img.SetAttr("src", path.Join(url.PathEscape(prefix), articleImagesDir, name))
+		if !seen[name] {
+			seen[name] = true
+			assets = append(assets, Asset{
+				LocalPath: filepath.Join(chapDir, prefix, articleImagesDir, name),
+				RemoteURL: u.String(),
+				Type:      AssetImage,
+				AssetID:   lecture.Asset.ID,
+				Variant:   name,
+			})
+		}
+	})
+
+	// standalone HTML page
+	contents, err := body.Html()
+	if err != nil {
+		return nil, err
+	}
+	var page bytes.Buffer
+	err = articleTemplate.Execute(&page, struct {
+		Title string
+		Body  template.HTML
+	}{lecture.Title, template.HTML(contents)})
+	if err != nil {
+		return nil, err
+	}
+	assets = append(assets, Asset{
+		LocalPath: filepath.Join(chapDir, prefix+".html"),
+		Contents:  page.Bytes(),
+		Type:      AssetArticle,
+		AssetID:   lecture.Asset.ID,
+	})
+
+	// and its Markdown version
+	md := "# " + lecture.Title + "\n\n" + htmlToMarkdown(body.Nodes...)
+	assets = append(assets, Asset{
+		LocalPath: filepath.Join(chapDir, prefix+".md"),
+		Contents:  []byte(md),
+		Type:      AssetArticle,
+		AssetID:   lecture.Asset.ID,
+	})
+	return assets, nil
+}
+
+// sanitizeArticle only keeps the allowed elements and attributes, with http, https,
+// mailto or relative URLs, since the exported pages are opened from the disk
+func sanitizeArticle(s *goquery.Selection) {
+	for _, n := range s.Nodes {
+		sanitizeNode(n)
+	}
+}
+
+func sanitizeNode(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.ElementNode:
+			switch {
+			case c.Namespace != "" || unsafeElements[c.Data]:
+				n.RemoveChild(c) // <- as SVG and MathML
+			case !articleElements[c.Data]:
+				// replaced by its (sanitized) contents
+				sanitizeNode(c)
+				for gc := c.FirstChild; gc != nil; gc = c.FirstChild {
+					c.RemoveChild(gc)
+					n.InsertBefore(gc, c)
+				}
+				n.RemoveChild(c)
+			default:
+				c.Attr = sanitizeAttributes(c.Data, c.Attr)
+				sanitizeNode(c)
+			}
+		case html.CommentNode:
+			n.RemoveChild(c)
+		}
+		c = next
+	}
+}
+
+func sanitizeAttributes(tag string, attrs []html.Attribute) []html.Attribute {
+	safe := attrs[:0]
+	for _, a := range attrs {
+		if a.Namespace != "" || !articleAttributes[a.Key] && !articleElementAttributes[tag][a.Key] {
+			continue
+		}
+		if urlAttributes[a.Key] && !isSafeURL(a.Val) {
+			continue
+		}
+		safe = append(safe, a)
+	}
+	return safe
+}
+
+// isSafeURL reports whether a link is relative, or uses the http, https or mailto scheme.
+// The ASCII whitespace and control characters are ignored, as browsers do.
+func isSafeURL(rawurl string) bool {
+	s := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, rawurl)
+	i := strings.IndexAny(s, ":/?#")
+	if i < 0 || s[i] != ':' {
+		return true // <- relative
+	}
+	switch strings.ToLower(s[:i]) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// articleImageName returns a stable local file name for an image URL
+func articleImageName(u *url.URL) string {
+	sum := sha256.Sum256([]byte(u.String()))
+	ext := strings.ToLower(path.Ext(u.Path))
+	switch ext {
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp":
+	default:
+		ext = ".png"
+	}
+	return hex.EncodeToString(sum[:8]) + ext
+}
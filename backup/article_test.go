@@ -0,0 +1,69 @@
+package backup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestSanitizeArticle(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{`<p class="intro" style="color:red" onclick="x()">Hi <b>there</b></p>`, `<p class="intro">Hi <b>there</b></p>`},
+		{`<script>alert(1)</script><style>p{}</style><iframe src="https://a"></iframe>ok`, `ok`},
+		{`<font color="red">kept</font> <center>text</center>`, `kept text`}, // <- unknown elements are unwrapped
+		{`<a href="https://example.com/a?b#c" target="_blank">link</a>`, `<a href="https://example.com/a?b#c">link</a>`},
+		{`<a href="mailto:me@example.com">mail</a><a href="../page.html">rel</a>`, `<a href="mailto:me@example.com">mail</a><a href="../page.html">rel</a>`},
+		{`<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href=" JaVa&#09;Script:alert(1)">x</a>`, `<a>x</a>`},
+		{"<a href=\"java\x01script:alert(1)\">x</a>", `<a>x</a>`},
+		{`<a href="vbscript:x">x</a>`, `<a>x</a>`},
+		{`<img src="data:text/html,&lt;script&gt;" srcset="javascript:x 1x" alt="a">`, `<img alt="a"/>`},
+		{`<img src="//cdn.example.com/i.png">`, `<img src="//cdn.example.com/i.png"/>`},
+		{`<form><button formaction="javascript:x">b</button></form>after`, `after`},
+		{`<svg><a xlink:href="javascript:x"><text>t</text></a></svg><math><mi>x</mi></math>end`, `end`},
+		{`<blockquote cite="javascript:x">q</blockquote>`, `<blockquote>q</blockquote>`},
+		{`<table><tr><td colspan="2" bgcolor="red">c</td></tr></table>`, `<table><tbody><tr><td colspan="2">c</td></tr></tbody></table>`},
+		{`<p>a<!-- comment -->b</p>`, `<p>ab</p>`},
+	}
+	for _, tt := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := doc.Find("body")
+		sanitizeArticle(body)
+		if got, _ := body.Html(); got != tt.want {
+			t.Errorf("sanitizeArticle(%q) = %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestIsSafeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com", true},
+		{"HTTP://example.com", true},
+		{"mailto:me@example.com", true},
+		{"images/a.png", true},
+		{"/a:b", true},
+		{"?q=a:b", true},
+		{"#top", true},
+		{"", true},
+		{"javascript:alert(1)", false},
+		{"\tjava\nscript:alert(1)", false},
+		{"\x00javascript:alert(1)", false},
+		{"data:text/html,x", false},
+		{"file:///etc/passwd", false},
+	}
+	for _, tt := range tests {
+		if got := isSafeURL(tt.url); got != tt.want {
+			t.Errorf("isSafeURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
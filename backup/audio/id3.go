@@ -0,0 +1,82 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strconv"
+	"unicode/utf16"
+)
+
+// Tags are the metadata written into the ID3 tag of an audio file
+type Tags struct {
+	Title  string // TIT2: the lecture
+	Album  string // TALB: the course
+	Group  string // TIT1: the chapter
+	Artist string // TPE1
+	Genre  string // TCON
+	Track  int    // TRCK: the lecture index
+	Disc   int    // TPOS: the chapter index
+}
+
+// WriteTo writes the tags as an ID3v2.3 tag (with UTF-16 texts), to be put at the start
+// of MP3 or AAC files
+func (t *Tags) WriteTo(w io.Writer) (int64, error) {
+	var frames bytes.Buffer
+	text := func(id, value string) {
+		if value == "" {
+			return
+		}
+		data := []byte{1, 0xff, 0xfe} // <- UTF-16 with BOM (little endian)
+		for _, u := range utf16.Encode([]rune(value)) {
+			data = append(data, byte(u), byte(u>>8))
+		}
+		var h [10]byte
+		copy(h[:], id)
+		binary.BigEndian.PutUint32(h[4:], uint32(len(data)))
+		frames.Write(h[:])
+		frames.Write(data)
+	}
+	text("TIT2", t.Title)
+	text("TALB", t.Album)
+	text("TIT1", t.Group)
+	text("TPE1", t.Artist)
+	text("TCON", t.Genre)
+	if t.Track > 0 {
+		text("TRCK", strconv.Itoa(t.Track))
+	}
+	if t.Disc > 0 {
+		text("TPOS", strconv.Itoa(t.Disc))
+	}
+
+	h := []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 0}
+	putSynchsafe(h[6:], frames.Len())
+	n, err := w.Write(h)
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := frames.WriteTo(w)
+	return int64(n) + m, err
+}
+
+// SkipID3 skips the ID3v2 tag at the start of r, if any
+func SkipID3(r *bufio.Reader) error {
+	h, err := r.Peek(10)
+	if err != nil || string(h[:3]) != "ID3" {
+		return nil // <- too short to have a tag
+	}
+	size := int(h[6])<<21 | int(h[7])<<14 | int(h[8])<<7 | int(h[9])
+	if h[5]&0x10 != 0 {
+		size += 10 // <- footer
+	}
+	_, err = r.Discard(10 + size)
+	return err
+}
+
+func putSynchsafe(b []byte, n int) {
+	b[0] = byte(n>>21) & 0x7f
+	b[1] = byte(n>>14) & 0x7f
+	b[2] = byte(n>>7) & 0x7f
+	b[3] = byte(n) & 0x7f
+}
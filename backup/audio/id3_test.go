@@ -0,0 +1,68 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestTags(t *testing.T) {
+	tags := &Tags{Title: "Intro", Album: "Course", Artist: "Jane Doe", Track: 3}
+	var buf bytes.Buffer
+	n, err := tags.WriteTo(&buf)
+	if err != nil || n != int64(buf.Len()) {
+		t.Fatalf("WriteTo() = %d, %v (%d bytes written)", n, err, buf.Len())
+	}
+	tag := buf.Bytes()
+	if string(tag[:5]) != "ID3\x03\x00" {
+		t.Errorf("header = % x", tag[:5])
+	}
+	size := int(tag[6])<<21 | int(tag[7])<<14 | int(tag[8])<<7 | int(tag[9])
+	if size != len(tag)-10 {
+		t.Errorf("tag size %d, want %d", size, len(tag)-10)
+	}
+
+	// the frames, as UTF-16 texts
+	frames := make(map[string]string)
+	for f := tag[10:]; len(f) >= 10; {
+		l := int(binary.BigEndian.Uint32(f[4:]))
+		data := f[10 : 10+l]
+		if data[0] != 1 || data[1] != 0xff || data[2] != 0xfe {
+			t.Errorf("%s: not UTF-16 with BOM", f[:4])
+		}
+		u := make([]uint16, (l-3)/2)
+		for i := range u {
+			u[i] = uint16(data[3+2*i]) | uint16(data[4+2*i])<<8
+		}
+		frames[string(f[:4])] = string(utf16.Decode(u))
+		f = f[10+l:]
+	}
+	want := map[string]string{"TIT2": "Intro", "TALB": "Course", "TPE1": "Jane Doe", "TRCK": "3"}
+	if len(frames) != len(want) {
+		t.Errorf("frames = %q, want %q", frames, want)
+	}
+	for id, v := range want {
+		if frames[id] != v {
+			t.Errorf("%s = %q, want %q", id, frames[id], v)
+		}
+	}
+
+	// SkipID3 drops the tag, and keeps the audio data
+	r := bufio.NewReader(bytes.NewReader(append(tag, "audio"...)))
+	if err := SkipID3(r); err != nil {
+		t.Fatal(err)
+	}
+	if rest, _ := ioutil.ReadAll(r); string(rest) != "audio" {
+		t.Errorf("SkipID3 left %q", rest)
+	}
+	r = bufio.NewReader(bytes.NewReader([]byte("no tag")))
+	if err := SkipID3(r); err != nil {
+		t.Fatal(err)
+	}
+	if rest, _ := ioutil.ReadAll(r); string(rest) != "no tag" {
+		t.Errorf("SkipID3 left %q", rest)
+	}
+}
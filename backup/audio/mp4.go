@@ -0,0 +1,388 @@
+// Package audio extracts the AAC audio track of MP4 videos, and writes the ID3 tags of
+// audio files.
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maximum size of the "moov" box loaded in memory
+const maxMovieSize = 64 << 20
+
+// ErrNoAudio is returned when the MP4 file has no AAC audio track
+var ErrNoAudio = errors.New("audio: no AAC track")
+
+// track holds the sample tables of an AAC track
+type track struct {
+	objectType  int // MPEG-4 audio object type (2 for AAC-LC)
+	freqIndex   int
+	channels    int
+	sampleSizes []uint32
+	chunks      []uint64 // chunk offsets
+	stsc        []stscEntry
+}
+
+type stscEntry struct {
+	firstChunk      uint32
+	samplesPerChunk uint32
+}
+
+// ExtractAAC reads the first AAC track of a MP4 file, and writes its samples to w as an
+// ADTS stream (that is, a ".aac" file)
+func ExtractAAC(w io.Writer, r io.ReaderAt, size int64) error {
+	moov, err := findBox(r, 0, size, "moov")
+	if err != nil {
+		return err
+	}
+	if moov.size > maxMovieSize {
+		return errors.New("audio: moov box too large")
+	}
+	data := make([]byte, moov.size)
+	if err := readAt(r, data, moov.offset); err != nil {
+		return err
+	}
+
+	// look for the sound track
+	var t *track
+	for _, trak := range children(data, "trak") {
+		if t, err = parseTrack(trak); err != nil {
+			return err
+		} else if t != nil {
+			break
+		}
+	}
+	if t == nil {
+		return ErrNoAudio
+	}
+	if len(t.chunks) == 0 || len(t.sampleSizes) == 0 {
+		return errors.New("audio: fragmented MP4 files are not supported")
+	}
+
+	// then copy all the samples, chunk by chunk
+	header := make([]byte, 7)
+	buf := make([]byte, 0, 8192)
+	sample := 0
+	for i, offset := range t.chunks {
+		n := t.samplesInChunk(uint32(i + 1))
+		for j := 0; j < n && sample < len(t.sampleSizes); j++ {
+			sz := int(t.sampleSizes[sample])
+			if cap(buf) < sz {
+				buf = make([]byte, sz)
+			}
+			buf = buf[:sz]
+			if err := readAt(r, buf, int64(offset)); err != nil {
+				return err
+			}
+			t.adtsHeader(header, sz)
+			if _, err := w.Write(header); err != nil {
+				return err
+			}
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+			offset += uint64(sz)
+			sample++
+		}
+	}
+	return nil
+}
+
+func (t *track) samplesInChunk(chunk uint32) int {
+	n := 0
+	for _, e := range t.stsc {
+		if e.firstChunk > chunk {
+			break
+		}
+		n = int(e.samplesPerChunk)
+	}
+	return n
+}
+
+// adtsHeader writes the 7-byte ADTS header (without CRC) of a frame
+func (t *track) adtsHeader(h []byte, size int) {
+	frameLen := size + 7
+	h[0] = 0xff
+	h[1] = 0xf1 // <- MPEG-4, layer 0, no CRC
+	h[2] = byte((t.objectType-1)<<6 | t.freqIndex<<2 | t.channels>>2)
+	h[3] = byte((t.channels&3)<<6 | frameLen>>11)
+	h[4] = byte(frameLen >> 3)
+	h[5] = byte((frameLen&7)<<5 | 0x1f)
+	h[6] = 0xfc
+}
+
+// parseTrack loads the sample tables of a "trak" box, or returns nil if it is not an
+// AAC track
+func parseTrack(trak []byte) (*track, error) {
+	mdia := child(trak, "mdia")
+	if hdlr := child(mdia, "hdlr"); len(hdlr) < 12 || string(hdlr[8:12]) != "soun" {
+		return nil, nil
+	}
+	stbl := child(child(mdia, "minf"), "stbl")
+	t := &track{}
+
+	// decoder config, in stsd > mp4a > esds
+	stsd := child(stbl, "stsd")
+	if len(stsd) < 8 {
+		return nil, nil
+	}
+	entries := stsd[8:]
+	if len(entries) < 8 || string(entries[4:8]) != "mp4a" {
+		return nil, nil
+	}
+	entry := box(entries)
+	if entry.size < 8+28 {
+		return nil, errors.New("audio: invalid mp4a box")
+	}
+	mp4a := entries[entry.headerSize:entry.size]
+	fields := 28 // <- audio sample entry fields, before the child boxes
+	switch binary.BigEndian.Uint16(mp4a[8:]) {
+	case 1:
+		fields += 16 // <- QuickTime sound description, version 1
+	case 2:
+		fields += 36
+	}
+	if len(mp4a) < fields {
+		return nil, errors.New("audio: invalid mp4a box")
+	}
+	esds := child(mp4a[fields:], "esds")
+	asc, err := parseESDS(esds)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.parseAudioConfig(asc); err != nil {
+		return nil, err
+	}
+
+	// sample sizes
+	if stsz := child(stbl, "stsz"); len(stsz) >= 12 {
+		uniform := binary.BigEndian.Uint32(stsz[4:])
+		count := int(binary.BigEndian.Uint32(stsz[8:]))
+		if uniform != 0 {
+			for i := 0; i < count; i++ {
+				t.sampleSizes = append(t.sampleSizes, uniform)
+			}
+		} else {
+			if len(stsz) < 12+4*count {
+				return nil, errors.New("audio: invalid stsz box")
+			}
+			for i := 0; i < count; i++ {
+				t.sampleSizes = append(t.sampleSizes, binary.BigEndian.Uint32(stsz[12+4*i:]))
+			}
+		}
+	}
+
+	// chunks
+	if stsc := child(stbl, "stsc"); len(stsc) >= 8 {
+		count := int(binary.BigEndian.Uint32(stsc[4:]))
+		if len(stsc) < 8+12*count {
+			return nil, errors.New("audio: invalid stsc box")
+		}
+		for i := 0; i < count; i++ {
+			e := stsc[8+12*i:]
+			t.stsc = append(t.stsc, stscEntry{binary.BigEndian.Uint32(e), binary.BigEndian.Uint32(e[4:])})
+		}
+	}
+	if stco := child(stbl, "stco"); len(stco) >= 8 {
+		count := int(binary.BigEndian.Uint32(stco[4:]))
+		if len(stco) < 8+4*count {
+			return nil, errors.New("audio: invalid stco box")
+		}
+		for i := 0; i < count; i++ {
+			t.chunks = append(t.chunks, uint64(binary.BigEndian.Uint32(stco[8+4*i:])))
+		}
+	} else if co64 := child(stbl, "co64"); len(co64) >= 8 {
+		count := int(binary.BigEndian.Uint32(co64[4:]))
+		if len(co64) < 8+8*count {
+			return nil, errors.New("audio: invalid co64 box")
+		}
+		for i := 0; i < count; i++ {
+			t.chunks = append(t.chunks, binary.BigEndian.Uint64(co64[8+8*i:]))
+		}
+	}
+	return t, nil
+}
+
+// parseAudioConfig reads the AudioSpecificConfig of the track
+func (t *track) parseAudioConfig(asc []byte) error {
+	if len(asc) < 2 {
+		return errors.New("audio: invalid AudioSpecificConfig")
+	}
+	bits := uint(asc[0])<<8 | uint(asc[1])
+	t.objectType = int(bits >> 11)
+	t.freqIndex = int(bits>>7) & 0xf
+	t.channels = int(bits>>3) & 0xf
+	if t.objectType == 5 || t.objectType == 29 {
+		// HE-AAC: the ADTS stream carries the underlying AAC-LC, at the core sampling rate
+		t.objectType = 2
+	}
+	if t.objectType < 1 || t.objectType > 4 {
+		return fmt.Errorf("audio: unsupported audio object type %d", t.objectType)
+	}
+	if t.freqIndex > 12 {
+		return errors.New("audio: explicit sampling frequencies are not supported")
+	}
+	return nil
+}
+
+// parseESDS returns the DecoderSpecificInfo (the AudioSpecificConfig) of an "esds" box
+func parseESDS(esds []byte) ([]byte, error) {
+	if len(esds) < 4 {
+		return nil, errors.New("audio: missing esds box")
+	}
+	d := esds[4:] // <- version and flags
+	for len(d) > 0 {
+		tag := d[0]
+		n, l := descriptorLength(d[1:])
+		d = d[1+n:]
+		if l > len(d) {
+			return nil, errors.New("audio: invalid esds box")
+		}
+		switch tag {
+		case 0x03: // ES_Descriptor
+			if len(d) < 3 {
+				return nil, errors.New("audio: invalid esds box")
+			}
+			flags := d[2]
+			skip := 3
+			if flags&0x80 != 0 {
+				skip += 2
+			}
+			if flags&0x40 != 0 && len(d) > skip {
+				skip += 1 + int(d[skip])
+			}
+			if flags&0x20 != 0 {
+				skip += 2
+			}
+			if skip > len(d) {
+				return nil, errors.New("audio: invalid esds box")
+			}
+			d = d[skip:]
+		case 0x04: // DecoderConfigDescriptor
+			if len(d) < 13 {
+				return nil, errors.New("audio: invalid esds box")
+			}
+			d = d[13:]
+		case 0x05: // DecoderSpecificInfo
+			return d[:l], nil
+		default:
+			d = d[l:]
+		}
+	}
+	return nil, errors.New("audio: missing AudioSpecificConfig")
+}
+
+// descriptorLength reads the variable-length size of a descriptor
+func descriptorLength(d []byte) (n, length int) {
+	for n < 4 && n < len(d) {
+		b := d[n]
+		n++
+		length = length<<7 | int(b&0x7f)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	return n, length
+}
+
+// boxRef locates a box in the file
+type boxRef struct {
+	offset int64 // of the box contents
+	size   int64 // of the box contents
+}
+
+// findBox looks for a top-level box of the file
+func findBox(r io.ReaderAt, offset, end int64, typ string) (*boxRef, error) {
+	h := make([]byte, 16)
+	for offset+8 <= end {
+		if err := readAt(r, h[:8], offset); err != nil {
+			return nil, err
+		}
+		size := int64(binary.BigEndian.Uint32(h))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			size = end - offset // <- last box of the file
+		case 1:
+			if err := readAt(r, h[8:16], offset+8); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(h[8:]))
+			headerSize = 16
+		}
+		if size < headerSize {
+			return nil, errors.New("audio: invalid MP4 box")
+		}
+		if string(h[4:8]) == typ {
+			return &boxRef{offset + headerSize, size - headerSize}, nil
+		}
+		offset += size
+	}
+	return nil, fmt.Errorf("audio: missing %s box", typ)
+}
+
+// readAt fills p from the given offset
+func readAt(r io.ReaderAt, p []byte, offset int64) error {
+	n, err := r.ReadAt(p, offset)
+	if n == len(p) {
+		return nil // <- ReaderAt may return io.EOF along with the last bytes
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// rawBox is a box in memory
+type rawBox struct {
+	typ        string
+	headerSize int
+	size       int // including the header
+}
+
+// box reads the header of the box at the start of data
+func box(data []byte) rawBox {
+	if len(data) < 8 {
+		return rawBox{}
+	}
+	b := rawBox{typ: string(data[4:8]), headerSize: 8, size: int(binary.BigEndian.Uint32(data))}
+	switch b.size {
+	case 0:
+		b.size = len(data)
+	case 1:
+		if len(data) < 16 {
+			return rawBox{}
+		}
+		b.size, b.headerSize = int(binary.BigEndian.Uint64(data[8:])), 16
+	}
+	if b.size < b.headerSize || b.size > len(data) {
+		return rawBox{}
+	}
+	return b
+}
+
+// children returns the contents of all the child boxes of the given type
+func children(data []byte, typ string) [][]byte {
+	var found [][]byte
+	for len(data) >= 8 {
+		b := box(data)
+		if b.size == 0 {
+			break
+		}
+		if b.typ == typ {
+			found = append(found, data[b.headerSize:b.size])
+		}
+		data = data[b.size:]
+	}
+	return found
+}
+
+// child returns the contents of the first child box of the given type, or nil
+func child(data []byte, typ string) []byte {
+	if c := children(data, typ); len(c) > 0 {
+		return c[0]
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+)
+
+// mkbox returns a MP4 box of the given type
+func mkbox(typ string, contents ...[]byte) []byte {
+	data := bytes.Join(contents, nil)
+	b := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint32(b, uint32(8+len(data)))
+	copy(b[4:], typ)
+	return append(b, data...)
+}
+
+func u32(values ...uint32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+// testMP4 returns a MP4 file with a video track, and an AAC-LC track (44.1 kHz, stereo)
+// of three samples in two chunks
+func testMP4() []byte {
+	ftyp := mkbox("ftyp", []byte("isom"), u32(0))
+	mdat := mkbox("mdat", []byte("abcdefghij"))
+	offset := uint32(len(ftyp) + 8)
+
+	video := mkbox("trak", mkbox("mdia", mkbox("hdlr", u32(0, 0), []byte("vide"))))
+	esds := mkbox("esds", u32(0),
+		[]byte{0x03, 22, 0, 1, 0},                      // <- ES_Descriptor
+		[]byte{0x04, 17, 0x40, 0x15}, make([]byte, 11), // <- DecoderConfigDescriptor
+		[]byte{0x05, 2, 0x12, 0x10}, // <- AudioSpecificConfig
+	)
+	mp4a := mkbox("mp4a", make([]byte, 28), esds)
+	stbl := mkbox("stbl",
+		mkbox("stsd", u32(0, 1), mp4a),
+		mkbox("stsz", u32(0, 0, 3, 3, 5, 2)),
+		mkbox("stsc", u32(0, 2, 1, 2, 1, 2, 1, 1)),
+		mkbox("stco", u32(0, 2, offset, offset+8)),
+	)
+	sound := mkbox("trak", mkbox("mdia", mkbox("hdlr", u32(0, 0), []byte("soun")), mkbox("minf", stbl)))
+	return bytes.Join([][]byte{ftyp, mdat, mkbox("moov", video, sound)}, nil)
+}
+
+func TestExtractAAC(t *testing.T) {
+	mp4 := testMP4()
+	var buf bytes.Buffer
+	if err := ExtractAAC(&buf, bytes.NewReader(mp4), int64(len(mp4))); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first frame header: AAC-LC, 44.1 kHz, 2 channels, 3+7 bytes
+	out := buf.Bytes()
+	if want := []byte{0xff, 0xf1, 0x50, 0x80, 0x01, 0x5f, 0xfc}; !bytes.Equal(out[:7], want) {
+		t.Errorf("ADTS header = % x, want % x", out[:7], want)
+	}
+	var samples []string
+	for len(out) >= 7 {
+		frameLen := int(out[3]&3)<<11 | int(out[4])<<3 | int(out[5])>>5
+		if out[0] != 0xff || out[1]&0xf0 != 0xf0 || frameLen < 7 || frameLen > len(out) {
+			t.Fatalf("invalid ADTS frame % x", out[:7])
+		}
+		samples = append(samples, string(out[7:frameLen]))
+		out = out[frameLen:]
+	}
+	if len(out) != 0 || len(samples) != 3 || samples[0] != "abc" || samples[1] != "defgh" || samples[2] != "ij" {
+		t.Errorf("samples = %q (%d bytes left)", samples, len(out))
+	}
+}
+
+func TestExtractAACErrors(t *testing.T) {
+	noAudio := append(mkbox("ftyp", []byte("isom")), mkbox("moov", mkbox("trak", mkbox("mdia", mkbox("hdlr", u32(0, 0), []byte("vide")))))...)
+	if err := ExtractAAC(ioutil.Discard, bytes.NewReader(noAudio), int64(len(noAudio))); err != ErrNoAudio {
+		t.Errorf("ExtractAAC() = %v, want ErrNoAudio", err)
+	}
+	noMovie := mkbox("ftyp", []byte("isom"))
+	if err := ExtractAAC(ioutil.Discard, bytes.NewReader(noMovie), int64(len(noMovie))); err == nil {
+		t.Error("ExtractAAC() should fail without a moov box")
+	}
+}
@@ -6,57 +6,126 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"mime"
 	"path/filepath"
-	"strconv"
 	"strings"
 
+	"github.com/ushu/udemy-backup/backup/captions"
+	"github.com/ushu/udemy-backup/backup/hls"
+	"github.com/ushu/udemy-backup/backup/resolution"
 	"github.com/ushu/udemy-backup/client"
-	"github.com/ushu/udemy-backup/client/lister"
 )
 
 type Backuper struct {
 	Client        *client.Client
 	RootDir       string
+	CourseDir     string // directory of the course (named in RootDir if empty)
 	LoadSubtitles bool
+	Resolution    int               // preferred resolution of the videos (0 for the highest)
+	Policy        resolution.Policy // selection of the resolution of the videos
+	Captions      captions.Options
+	AudioOnly     bool       // only backup the audio of the lectures (as a podcast)
+	Paths         *Sanitizer // makes the file names valid (POSIX if nil)
+	Naming        *Naming    // templates of the file names (the default names if nil)
+	Layout        Layout
+
+	// Warn receives the non-fatal issues found while listing the assets (as unavailable
+	// resolutions)
+	Warn func(err error)
+
+	episodes     map[string]int    // episode numbers of the curriculum items, for the show layout
+	prefixes     map[string]string // prefix of each curriculum item (see uniquePrefix)
+	prefixed     map[string]bool   // paths of the prefixes given so far
+	namingFailed map[string]bool   // naming templates that failed (see formatName)
+	details      *client.Course    // details of the course, see loadDetails
 }
 
 type Asset struct {
 	LocalPath string
 	RemoteURL string
 	Contents  []byte
+	HLS       bool // RemoteURL points to an HLS playlist, whose segments get joined into LocalPath
+
+	// Build writes the contents of assets derived from other files (they are built once
+	// all the downloads are done)
+	Build func(ctx context.Context, w io.Writer) error
+	// Refresh forces the asset to be written again on each backup (its contents depend
+	// on the rest of the backup, so it is built after all the other derived assets)
+	Refresh bool
+
+	// origin of the asset
+	Type      AssetType
+	CourseID  int
+	ChapterID int
+	LectureID int    // ID of the curriculum item (lecture, quiz or practice)
+	AssetID   int    // ID of the Udemy asset (0 for generated contents)
+	Variant   string // distinguishes the files of a same Udemy asset (caption locale, file index...)
+}
+
+// Key identifies the asset across backups, independently of its local path
+func (a *Asset) Key() string {
+	return assetKey(a.Type, a.LectureID, a.AssetID, a.Variant, a.LocalPath)
+}
+
+func assetKey(t AssetType, lectureID, assetID int, variant, path string) string {
+	return fmt.Sprintf("%s/%d/%d/%s/%s", t, lectureID, assetID, variant, filepath.Ext(path))
 }
 
+// AssetType describes the contents of an Asset
+type AssetType string
+
+const (
+	AssetVideo    AssetType = "video"
+	AssetAudio    AssetType = "audio"
+	AssetCaption  AssetType = "caption"
+	AssetFile     AssetType = "file"
+	AssetLinks    AssetType = "links"
+	AssetArticle  AssetType = "article"
+	AssetImage    AssetType = "image"
+	AssetSlide    AssetType = "slide"
+	AssetQuiz     AssetType = "quiz"
+	AssetExercise AssetType = "exercise"
+	AssetPractice AssetType = "practice"
+	AssetCourse   AssetType = "course" // course description
+)
+
 type link struct {
 	Title string
 	URL   string
 }
 
 func New(client *client.Client, rootDir string, loadSubtitles bool) *Backuper {
-	return &Backuper{client, rootDir, loadSubtitles}
+	return &Backuper{Client: client, RootDir: rootDir, LoadSubtitles: loadSubtitles}
 }
 
 func (b *Backuper) ListCourseAssets(ctx context.Context, course *client.Course) ([]Asset, []string, error) {
 	var directories []string
 	var assets []Asset
 
+	// the course listings only give the title of the course
+	if err := b.loadDetails(ctx, course); err != nil {
+		return assets, directories, err
+	}
+
 	// then we list all the lectures for the course
-	lst := lister.New(b.Client)
-	lectures, err := lst.LoadFullCurriculum(ctx, course.ID)
+	lectures, err := b.Client.LoadFullCurriculum(ctx, course.ID)
 	if err != nil {
 		return assets, directories, err
 	}
+	b.episodes = b.episodeNumbers(lectures)
+
 	// we start by creating the necessary directories to hold all the lectures the root dir
-	courseDir := getCourseDirectory(b.RootDir, course)
+	courseDir := b.getCourseDirectory(course)
 	directories = append(directories, courseDir)
 
 	// now we parse the curriculum
 	for _, l := range lectures {
 		if chap, ok := l.(*client.Chapter); ok {
-			chapDir := getChapterDirectory(b.RootDir, course, chap)
+			chapDir := b.getChapterDirectory(course, chap)
 			directories = append(directories, chapDir)
 		} else if lecture, ok := l.(*client.Lecture); ok {
-			courseAssets, courseDirs := b.ListLectureAssets(course, lecture)
+			courseAssets, courseDirs := b.ListLectureAssets(ctx, course, lecture)
 			if err != nil {
 				return assets, directories, err
 			}
@@ -64,26 +133,73 @@ func (b *Backuper) ListCourseAssets(ctx context.Context, course *client.Course)
 			for _, courseDir := range courseDirs {
 				directories = append(directories, courseDir)
 			}
+		} else if quiz, ok := l.(*client.Quiz); ok && !b.AudioOnly {
+			var quizAssets []Asset
+			var quizDirs []string
+			if quiz.Type == "coding-exercise" {
+				quizAssets, quizDirs, err = b.ListCodingExerciseAssets(ctx, course, quiz)
+			} else {
+				quizAssets, err = b.ListQuizAssets(ctx, course, quiz)
+			}
+			if err != nil && ctx.Err() != nil {
+				return assets, directories, err
+			} else if err != nil {
+				b.warn(fmt.Errorf("%s: %v, skipping the quiz", quiz.Title, err)) // <- as a locked quiz
+				continue
+			}
+			assets = append(assets, quizAssets...)
+			directories = append(directories, quizDirs...)
+		} else if practice, ok := l.(*client.Practice); ok && !b.AudioOnly {
+			assets = append(assets, b.ListPracticeAssets(course, practice)...)
 		}
 	}
 
+	// the HLS videos are named after the container of their segments
+	b.resolveStreams(ctx, assets)
+
+	// the file names given by Udemy may collide
+	b.allocatePaths(assets)
+
+	// and the description of the course
+	metadataAssets, err := b.ListCourseMetadataAssets(ctx, course, lectures)
+	if err != nil {
+		return assets, directories, err
+	}
+	assets = append(assets, metadataAssets...)
+
+	// and the offline player, listing all the above
+	assets = append(assets, b.ListPlayerAssets(course, lectures, assets)...)
+
+	// and the playlists, for the media players
+	assets = append(assets, b.ListPlaylistAssets(course, lectures, assets)...)
+
 	return assets, directories, nil
 }
 
-func (b *Backuper) ListLectureAssets(course *client.Course, lecture *client.Lecture) ([]Asset, []string) {
+func (b *Backuper) ListLectureAssets(ctx context.Context, course *client.Course, lecture *client.Lecture) ([]Asset, []string) {
 	var directories []string
 	var assets []Asset
 
-	chapDir := getChapterDirectory(b.RootDir, course, lecture.Chapter)
-	prefix := getLecturePrefix(lecture)
+	chapDir := b.getChapterDirectory(course, lecture.Chapter)
+	videos := findVideos(lecture)
+	video, ok := filterVideos(videos, b.Resolution, b.Policy)
+	prefix := b.getLecturePrefix(course, chapDir, lecture, video)
+
+	// in audio-only mode, the audio track replaces all the other assets
+	if b.AudioOnly {
+		return tagLectureAssets(course, lecture, b.listPodcastAssets(course, lecture, chapDir, prefix)), nil
+	}
 
 	// flag for building the (optional) assets dir
 	assetsDirectoryBuilt := false
 
 	// now we traverse the Lecture struct, and enqueue all the necessary work
 	// first the video stream, if any
-	videos := findVideos(lecture)
-	video := filterVideos(videos, 1080)
+	if !ok && video != nil {
+		b.warn(fmt.Errorf("%s: %dp is not available, downloading %s", lecture.Title, b.Resolution, video.Label))
+	} else if !ok && findPlaylist(videos) == nil {
+		b.warn(fmt.Errorf("%s: %dp is not available, skipping the video", lecture.Title, b.Resolution))
+	}
 	if video != nil {
 		// enqueue download of the video
 		ext := ".mp4"
@@ -93,38 +209,44 @@ func (b *Backuper) ListLectureAssets(course *client.Course, lecture *client.Lect
 		assets = append(assets, Asset{
 			LocalPath: filepath.Join(chapDir, prefix+ext),
 			RemoteURL: video.File,
+			Type:      AssetVideo,
+			AssetID:   lecture.Asset.ID,
 		})
-
+	} else if stream := findPlaylist(videos); stream != nil {
+		// only an HLS stream is available: the segments will be joined into a single file
+		assets = append(assets, Asset{
+			LocalPath: filepath.Join(chapDir, prefix+".ts"),
+			RemoteURL: stream.File,
+			HLS:       true,
+			Type:      AssetVideo,
+			AssetID:   lecture.Asset.ID,
+		})
+		video = stream
+	}
+	if video != nil {
 		// when the stream is found, we also look up the captions
 		if b.LoadSubtitles && lecture.Asset != nil && len(lecture.Asset.Captions) > 0 {
-			assetsDir := filepath.Join(chapDir, prefix)
-			if !assetsDirectoryBuilt {
-				directories = append(directories, assetsDir)
-				assetsDirectoryBuilt = true
-			}
-			for _, c := range lecture.Asset.Captions {
-				ext := filepath.Ext(c.FileName)
-				locale := c.Locale.Locale
-				captionFileName := fmt.Sprintf("%s.%s%s", prefix, locale, ext)
-				assets = append(assets, Asset{
-					LocalPath: filepath.Join(assetsDir, captionFileName),
-					RemoteURL: c.URL,
-				})
+			captionsDir := chapDir // <- next to the video
+			if b.Captions.Placement != captions.NextToVideo {
+				captionsDir = filepath.Join(chapDir, prefix)
+				if !assetsDirectoryBuilt {
+					directories = append(directories, captionsDir)
+					assetsDirectoryBuilt = true
+				}
 			}
+			assets = append(assets, b.listCaptionAssets(lecture, captionsDir, prefix)...)
 		}
 	}
 
 	// and the audio files
-	audio := filterAudio(videos)
+	audio := findAudio(lecture, videos)
 	if audio != nil {
 		// enqueue download of the audio
-		ext := ".mp3"
-		if exts, _ := mime.ExtensionsByType(audio.Type); len(exts) > 0 {
-			ext = exts[0]
-		}
 		assets = append(assets, Asset{
-			LocalPath: filepath.Join(chapDir, prefix+ext),
+			LocalPath: filepath.Join(chapDir, prefix+audioExt(audio.Type)),
 			RemoteURL: audio.File,
+			Type:      AssetAudio,
+			AssetID:   lecture.Asset.ID,
 		})
 	}
 
@@ -136,11 +258,29 @@ func (b *Backuper) ListLectureAssets(course *client.Course, lecture *client.Lect
 			directories = append(directories, assetsDir)
 			assetsDirectoryBuilt = true
 		}
-		for _, a := range otherAssets {
-			assets = append(assets, Asset{
-				LocalPath: filepath.Join(assetsDir, lecture.Asset.Title),
-				RemoteURL: a.File,
-			})
+		assets = append(assets, b.listFileAssets(ctx, assetsDir, lecture.Asset, otherAssets)...)
+	}
+
+	// articles are exported as HTML and Markdown, next to the videos
+	if lecture.Asset.AssetType == "Article" && lecture.Asset.Body != "" {
+		if articleAssets, err := listArticleAssets(lecture, chapDir, prefix); err != nil {
+			b.warn(fmt.Errorf("%s: could not export the article: %v", lecture.Title, err))
+		} else {
+			for _, a := range articleAssets {
+				if a.Type == AssetImage {
+					directories = append(directories, filepath.Join(chapDir, prefix, articleImagesDir))
+					break
+				}
+			}
+			assets = append(assets, articleAssets...)
+		}
+	}
+
+	// presentations: the slide images, assembled into a PDF
+	if len(lecture.Asset.SlideUrls) > 0 {
+		if slideAssets, slideDir := listSlideAssets(lecture, chapDir, prefix); len(slideAssets) > 0 {
+			directories = append(directories, slideDir)
+			assets = append(assets, slideAssets...)
 		}
 	}
 
@@ -175,13 +315,8 @@ func (b *Backuper) ListLectureAssets(course *client.Course, lecture *client.Lect
 			case "E-Book":
 				files = a.DownloadUrls.Ebook
 			}
-			// now we grab the file, into the assets directory
-			for _, f := range files {
-				assets = append(assets, Asset{
-					LocalPath: filepath.Join(assetsDir, a.Title),
-					RemoteURL: f.File,
-				})
-			}
+			// now we grab the files, into the assets directory
+			assets = append(assets, b.listFileAssets(ctx, assetsDir, a, files)...)
 		}
 		// finally, if we found one or more links, we create a "links.txt" file
 		if len(links) > 0 {
@@ -189,11 +324,24 @@ func (b *Backuper) ListLectureAssets(course *client.Course, lecture *client.Lect
 			assets = append(assets, Asset{
 				LocalPath: filepath.Join(assetsDir, "links.txt"),
 				Contents:  contents,
+				Type:      AssetLinks,
 			})
 		}
 	}
 
-	return assets, directories
+	return tagLectureAssets(course, lecture, assets), directories
+}
+
+// tagLectureAssets tags all the assets with the lecture they belong to
+func tagLectureAssets(course *client.Course, lecture *client.Lecture, assets []Asset) []Asset {
+	for i := range assets {
+		assets[i].CourseID = course.ID
+		assets[i].LectureID = lecture.ID
+		if lecture.Chapter != nil {
+			assets[i].ChapterID = lecture.Chapter.ID
+		}
+	}
+	return assets
 }
 
 func findVideos(lecture *client.Lecture) []*client.Video {
@@ -219,39 +367,66 @@ func findOtherAssets(lecture *client.Lecture) []*client.File {
 	return nil
 }
 
-func filterVideos(videos []*client.Video, resolution int) *client.Video {
-	var video *client.Video
-	currentRes := 0
+// filterVideos selects a video among the downloadable ones, following the resolution
+// policy: ok is false when the preferred resolution is not available
+func filterVideos(videos []*client.Video, preferred int, policy resolution.Policy) (*client.Video, bool) {
+	var candidates []*client.Video
+	var heights []int
 	for _, v := range videos {
 		if !strings.HasPrefix(v.Type, "video/") {
 			continue
 		}
-		vres, err := strconv.Atoi(v.Label)
-		if err != nil {
-			continue
-		}
-		if resolution > 0 && vres == resolution {
-			// perfect match
-			return v
-		}
-		// or find the highest resolution available
-		if vres > currentRes {
-			video = v
-			currentRes = vres
-		}
+		candidates = append(candidates, v)
+		heights = append(heights, resolution.ParseLabel(v.Label)) // <- 0 for "Auto"
 	}
-	return video
+	if len(candidates) == 0 {
+		return nil, true // <- nothing to select from (HLS streams are handled separately)
+	}
+	i, ok := resolution.Select(heights, preferred, policy)
+	if i < 0 {
+		return nil, ok
+	}
+	return candidates[i], ok
 }
 
-func filterAudio(videos []*client.Video) *client.Video {
+func findPlaylist(videos []*client.Video) *client.Video {
 	for _, v := range videos {
-		if !strings.HasPrefix(v.Type, "audio/") {
+		if hls.IsPlaylist(v.Type) {
 			return v
 		}
 	}
 	return nil
 }
 
+// resolveStreams loads the media playlists of the HLS videos, to give them the extension
+// of their container: ".ts", or ".mp4" for fragmented MP4 streams
+func (b *Backuper) resolveStreams(ctx context.Context, assets []Asset) {
+	for i, a := range assets {
+		if !a.HLS {
+			continue
+		}
+		media, err := b.hlsDownloader().LoadMediaPlaylist(ctx, a.RemoteURL)
+		if err != nil {
+			b.warn(fmt.Errorf("%s: %v", filepath.Base(a.LocalPath), err))
+			continue // <- keeps ".ts", the download will report the error
+		}
+		assets[i].LocalPath = strings.TrimSuffix(a.LocalPath, ".ts") + media.Ext()
+	}
+}
+
+// hlsDownloader returns a downloader of the HLS streams, with the resolution settings
+func (b *Backuper) hlsDownloader() *hls.Downloader {
+	d := hls.NewDownloader(b.Client.HTTPClient, b.Resolution)
+	d.Policy = b.Policy
+	return d
+}
+
+func (b *Backuper) warn(err error) {
+	if b.Warn != nil {
+		b.Warn(err)
+	}
+}
+
 func linksToFileContents(links []*link) []byte {
 	w := new(bytes.Buffer)
 	for _, link := range links {
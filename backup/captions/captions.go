@@ -0,0 +1,154 @@
+// Package captions selects the captions of the lectures, and converts them from WebVTT
+// to SubRip (SRT).
+package captions
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+// Format is the format of the caption files written by the backup
+type Format string
+
+const (
+	VTT  Format = "vtt"  // the WebVTT files served by Udemy
+	SRT  Format = "srt"  // converted to SubRip
+	Both Format = "both" // WebVTT and SubRip files
+)
+
+// AutoMode tells how the auto-generated captions are handled
+type AutoMode string
+
+const (
+	// PreferHuman keeps the auto-generated captions only for the locales without
+	// human-made captions
+	PreferHuman AutoMode = "prefer-human"
+	// HumanOnly drops all the auto-generated captions
+	HumanOnly AutoMode = "human-only"
+	// All keeps all the captions (the auto-generated ones get an ".auto" suffix)
+	All AutoMode = "all"
+)
+
+// Placement tells where the caption files are written
+type Placement string
+
+const (
+	// InFolder writes the captions into the assets folder of the lecture
+	InFolder Placement = "folder"
+	// NextToVideo writes the captions next to the video, with the same base name (so
+	// that the players find them automatically)
+	NextToVideo Placement = "video"
+)
+
+// Options configures the selection and conversion of captions
+type Options struct {
+	Locales   []string // allow-list of locales (as "en" or "en_US"), empty for all
+	Format    Format
+	Auto      AutoMode
+	Strip     bool // remove the styling (markup and cue settings) from the WebVTT files
+	Placement Placement
+}
+
+// Validate checks the options, and fills in the default values
+func (o *Options) Validate() error {
+	switch o.Format {
+	case "":
+		o.Format = VTT
+	case VTT, SRT, Both:
+	default:
+		return fmt.Errorf("captions: invalid format %q", o.Format)
+	}
+	switch o.Auto {
+	case "":
+		o.Auto = PreferHuman
+	case PreferHuman, HumanOnly, All:
+	default:
+		return fmt.Errorf("captions: invalid auto mode %q", o.Auto)
+	}
+	switch o.Placement {
+	case "":
+		o.Placement = InFolder
+	case InFolder, NextToVideo:
+	default:
+		return fmt.Errorf("captions: invalid placement %q", o.Placement)
+	}
+	return nil
+}
+
+// Formats returns the extensions of the files to write for each caption
+func (o *Options) Formats() []string {
+	switch o.Format {
+	case SRT:
+		return []string{".srt"}
+	case Both:
+		return []string{".vtt", ".srt"}
+	}
+	return []string{".vtt"}
+}
+
+// IsAuto reports whether the caption was generated automatically (as opposed to written
+// by the instructor or a translator)
+func IsAuto(c *client.Caption) bool {
+	return c.Source == "auto" || strings.Contains(strings.ToLower(c.VideoLabel), "[auto]")
+}
+
+// MatchLocale reports whether the locale is allowed: "en" allows all the English locales,
+// while "en_US" only allows American English.
+func MatchLocale(locale string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	locale = normalizeLocale(locale)
+	for _, a := range allowed {
+		a = normalizeLocale(a)
+		if locale == a || strings.HasPrefix(locale, a+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeLocale(l string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(l), "-", "_", -1))
+}
+
+// Select returns the captions to backup, in their original order
+func Select(captions []*client.Caption, opts Options) []*client.Caption {
+	human := make(map[string]bool)
+	for _, c := range captions {
+		if c != nil && !IsAuto(c) {
+			human[normalizeLocale(c.Locale.Locale)] = true
+		}
+	}
+	var selected []*client.Caption
+	for _, c := range captions {
+		if c == nil || c.URL == "" || !MatchLocale(c.Locale.Locale, opts.Locales) {
+			continue
+		}
+		if IsAuto(c) {
+			if opts.Auto == HumanOnly || (opts.Auto != All && human[normalizeLocale(c.Locale.Locale)]) {
+				continue
+			}
+		}
+		selected = append(selected, c)
+	}
+	return selected
+}
+
+// Convert reads a WebVTT file, and writes it in the format given by ext (".vtt" or ".srt")
+func Convert(w io.Writer, r io.Reader, ext string, strip bool) error {
+	cues, err := ParseVTT(r)
+	if err != nil {
+		return err
+	}
+	switch ext {
+	case ".srt":
+		return WriteSRT(w, cues, strip)
+	case ".vtt":
+		return WriteVTT(w, cues, strip)
+	}
+	return fmt.Errorf("captions: unsupported format %q", ext)
+}
@@ -0,0 +1,77 @@
+package captions
+
+import (
+	"testing"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+func TestMatchLocale(t *testing.T) {
+	tests := []struct {
+		locale  string
+		allowed []string
+		want    bool
+	}{
+		{"en_US", nil, true},
+		{"en_US", []string{"en"}, true},
+		{"en-GB", []string{"EN"}, true},
+		{"en_US", []string{"en_GB"}, false},
+		{"es_ES", []string{"en", "es-ES"}, true},
+		{"eng", []string{"en"}, false},
+	}
+	for _, tt := range tests {
+		if got := MatchLocale(tt.locale, tt.allowed); got != tt.want {
+			t.Errorf("MatchLocale(%q, %q) = %v, want %v", tt.locale, tt.allowed, got, tt.want)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	caption := func(locale, source string) *client.Caption {
+		return &client.Caption{Locale: client.Locale{Locale: locale}, Source: source, URL: "https://example.com/" + locale + "/" + source}
+	}
+	captions := []*client.Caption{
+		caption("en_US", "auto"),
+		caption("en_US", "manual"),
+		caption("fr_FR", "auto"),
+		caption("es_ES", "manual"),
+		{Locale: client.Locale{Locale: "de_DE"}}, // <- no URL
+		nil,
+	}
+	tests := []struct {
+		opts Options
+		want []string
+	}{
+		{Options{Auto: PreferHuman}, []string{"en_US/manual", "fr_FR/auto", "es_ES/manual"}},
+		{Options{Auto: HumanOnly}, []string{"en_US/manual", "es_ES/manual"}},
+		{Options{Auto: All}, []string{"en_US/auto", "en_US/manual", "fr_FR/auto", "es_ES/manual"}},
+		{Options{Auto: PreferHuman, Locales: []string{"en", "fr"}}, []string{"en_US/manual", "fr_FR/auto"}},
+	}
+	for _, tt := range tests {
+		got := Select(captions, tt.opts)
+		if len(got) != len(tt.want) {
+			t.Errorf("%+v: %d captions, want %q", tt.opts, len(got), tt.want)
+			continue
+		}
+		for i, c := range got {
+			if c.URL != "https://example.com/"+tt.want[i] {
+				t.Errorf("%+v: caption %d is %s, want %s", tt.opts, i, c.URL, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	var o Options
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if o.Format != VTT || o.Auto != PreferHuman || o.Placement != InFolder {
+		t.Errorf("defaults = %+v", o)
+	}
+	for _, o := range []Options{{Format: "ass"}, {Auto: "none"}, {Placement: "root"}} {
+		if err := o.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", o)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package captions
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cue is a caption displayed between Start and End
+type Cue struct {
+	ID       string
+	Start    time.Duration
+	End      time.Duration
+	Settings string // WebVTT cue settings, as "align:start position:10%"
+	Text     string
+}
+
+var (
+	tagRegexp = regexp.MustCompile(`</?([a-zA-Z]*)[^>]*>`) // <- also matches the karaoke-style timestamps
+	entities  = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&nbsp;", " ", "&lrm;", "\u200e", "&rlm;", "\u200f")
+)
+
+// ParseVTT reads the cues of a WebVTT file. The NOTE, STYLE and REGION blocks are ignored.
+func ParseVTT(r io.Reader) ([]*Cue, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	// split the file into blocks of lines
+	var blocks [][]string
+	var block []string
+	first := true
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff") // <- BOM
+			if !strings.HasPrefix(line, "WEBVTT") {
+				return nil, fmt.Errorf("captions: missing WEBVTT header")
+			}
+			first = false
+		}
+		if strings.TrimSpace(line) == "" {
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+				block = nil
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+	if first {
+		return nil, fmt.Errorf("captions: empty file")
+	}
+
+	var cues []*Cue
+	for _, b := range blocks[1:] { // <- the first block is the header
+		if strings.HasPrefix(b[0], "NOTE") || strings.HasPrefix(b[0], "STYLE") || strings.HasPrefix(b[0], "REGION") {
+			continue
+		}
+		c := &Cue{}
+		if !strings.Contains(b[0], "-->") {
+			c.ID, b = b[0], b[1:]
+		}
+		if len(b) == 0 {
+			continue
+		}
+		var err error
+		if c.Start, c.End, c.Settings, err = parseTiming(b[0]); err != nil {
+			return nil, err
+		}
+		c.Text = strings.Join(b[1:], "\n")
+		cues = append(cues, c)
+	}
+	return cues, nil
+}
+
+func parseTiming(line string) (start, end time.Duration, settings string, err error) {
+	parts := strings.SplitN(line, "-->", 2)
+	if len(parts) != 2 {
+		return 0, 0, "", fmt.Errorf("captions: invalid timing %q", line)
+	}
+	if start, err = parseTimestamp(strings.TrimSpace(parts[0])); err != nil {
+		return
+	}
+	fields := strings.Fields(parts[1])
+	if len(fields) == 0 {
+		return 0, 0, "", fmt.Errorf("captions: invalid timing %q", line)
+	}
+	if end, err = parseTimestamp(fields[0]); err != nil {
+		return
+	}
+	return start, end, strings.Join(fields[1:], " "), nil
+}
+
+// parseTimestamp parses "hh:mm:ss.ttt" or "mm:ss.ttt" timestamps
+func parseTimestamp(s string) (time.Duration, error) {
+	parts := strings.Split(strings.Replace(s, ",", ".", 1), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("captions: invalid timestamp %q", s)
+	}
+	var d time.Duration
+	for i, p := range parts {
+		if i == len(parts)-1 {
+			sec, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return 0, fmt.Errorf("captions: invalid timestamp %q", s)
+			}
+			d = d*60 + time.Duration(sec*float64(time.Second)+0.5)
+			break
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, fmt.Errorf("captions: invalid timestamp %q", s)
+		}
+		d = d*60 + time.Duration(n)*time.Second
+	}
+	return d, nil
+}
+
+// StripStyling removes all the markup (classes, voices, bold...) from the text of a cue
+func StripStyling(text string) string {
+	return tagRegexp.ReplaceAllString(text, "")
+}
+
+// srtText converts the text of a WebVTT cue to SRT: only the <b>, <i> and <u> tags are
+// kept (unless strip is set), and the entities are decoded
+func srtText(text string, strip bool) string {
+	text = tagRegexp.ReplaceAllStringFunc(text, func(tag string) string {
+		name := tagRegexp.FindStringSubmatch(tag)[1]
+		if !strip && (name == "b" || name == "i" || name == "u") {
+			if strings.HasPrefix(tag, "</") {
+				return "</" + name + ">"
+			}
+			return "<" + name + ">" // <- drop the classes, as in <i.loud>
+		}
+		return ""
+	})
+	return entities.Replace(text)
+}
+
+// WriteSRT writes the cues as a SubRip file
+func WriteSRT(w io.Writer, cues []*Cue, strip bool) error {
+	bw := bufio.NewWriter(w)
+	n := 0
+	for _, c := range cues {
+		text := strings.TrimSpace(srtText(c.Text, strip))
+		if text == "" {
+			continue
+		}
+		n++
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", n, formatTimestamp(c.Start, ','), formatTimestamp(c.End, ','), text)
+	}
+	return bw.Flush()
+}
+
+// WriteVTT writes the cues as a WebVTT file: when strip is set, the cue settings and the
+// markup are removed
+func WriteVTT(w io.Writer, cues []*Cue, strip bool) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "WEBVTT\n\n")
+	for _, c := range cues {
+		text, settings := c.Text, c.Settings
+		if strip {
+			text, settings = strings.TrimSpace(StripStyling(text)), ""
+			if text == "" {
+				continue
+			}
+		}
+		if c.ID != "" {
+			fmt.Fprintln(bw, c.ID)
+		}
+		fmt.Fprintf(bw, "%s --> %s", formatTimestamp(c.Start, '.'), formatTimestamp(c.End, '.'))
+		if settings != "" {
+			fmt.Fprintf(bw, " %s", settings)
+		}
+		fmt.Fprintf(bw, "\n%s\n\n", text)
+	}
+	return bw.Flush()
+}
+
+func formatTimestamp(d time.Duration, sep byte) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
@@ -0,0 +1,91 @@
+package captions
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleVTT = "\ufeffWEBVTT - Lecture\r\n" +
+	"\r\n" +
+	"NOTE a comment\r\n" +
+	"\r\n" +
+	"STYLE\r\n" +
+	"::cue { color: yellow }\r\n" +
+	"\r\n" +
+	"intro\r\n" +
+	"00:01.000 --> 00:04.500 align:start position:10%\r\n" +
+	"<v Teacher>Hello &amp; <i.loud>welcome</i></v>\r\n" +
+	"<b>everyone</b>\r\n" +
+	"\r\n" +
+	"01:02:03.004 --> 01:02:05.000\r\n" +
+	"<c.yellow>Bye</c> <00:02:04.000>now\r\n" +
+	"\r\n" +
+	"00:10.000 --> 00:11.000\r\n" +
+	"<c></c>\r\n"
+
+func TestParseVTT(t *testing.T) {
+	cues, err := ParseVTT(strings.NewReader(sampleVTT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cues) != 3 {
+		t.Fatalf("%d cues, want 3", len(cues))
+	}
+	c := cues[0]
+	if c.ID != "intro" || c.Start != time.Second || c.End != 4500*time.Millisecond || c.Settings != "align:start position:10%" {
+		t.Errorf("cue = %+v", c)
+	}
+	if want := time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond; cues[1].Start != want {
+		t.Errorf("start = %v, want %v", cues[1].Start, want)
+	}
+
+	for _, vtt := range []string{"", "1\n00:01.000 --> 00:02.000\nno header\n", "WEBVTT\n\n00:xx.000 --> 00:02.000\nbad\n"} {
+		if _, err := ParseVTT(strings.NewReader(vtt)); err == nil {
+			t.Errorf("ParseVTT(%q) should fail", vtt)
+		}
+	}
+}
+
+func TestWriteSRT(t *testing.T) {
+	cues, err := ParseVTT(strings.NewReader(sampleVTT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		strip bool
+		want  string
+	}{
+		{false, "1\n00:00:01,000 --> 00:00:04,500\nHello & <i>welcome</i>\n<b>everyone</b>\n\n" +
+			"2\n01:02:03,004 --> 01:02:05,000\nBye now\n\n"},
+		{true, "1\n00:00:01,000 --> 00:00:04,500\nHello & welcome\neveryone\n\n" +
+			"2\n01:02:03,004 --> 01:02:05,000\nBye now\n\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := WriteSRT(&buf, cues, tt.strip); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("WriteSRT(strip=%v) =\n%s\nwant\n%s", tt.strip, got, tt.want)
+		}
+	}
+}
+
+func TestWriteVTT(t *testing.T) {
+	cues, err := ParseVTT(strings.NewReader(sampleVTT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteVTT(&buf, cues, true); err != nil {
+		t.Fatal(err)
+	}
+	want := "WEBVTT\n\n" +
+		"intro\n00:00:01.000 --> 00:00:04.500\nHello &amp; welcome\neveryone\n\n" +
+		"01:02:03.004 --> 01:02:05.000\nBye now\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteVTT(strip) =\n%s\nwant\n%s", got, want)
+	}
+}
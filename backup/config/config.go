@@ -0,0 +1,172 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/viper"
+	"github.com/ushu/udemy-backup/backup/captions"
+	"github.com/ushu/udemy-backup/backup/resolution"
+	"github.com/ushu/udemy-backup/client"
+)
+
+// Config holds all the options of a backup
+type Config struct {
+	Client              *client.Client
+	RootDir             string            // output directory
+	CourseDir           string            // directory of the course, instead of the one named in RootDir (to repair a backup)
+	PreferredResolution int               // 0 means "highest available"
+	ResolutionPolicy    resolution.Policy // how the resolution of the videos is selected
+	NumWorkers          int               // number of parallel downloads
+	Restart             bool              // re-download all the files, even existing ones
+	LoadSubtitles       bool              // download the captions along with the videos
+	Captions            captions.Options  // selection and format of the captions
+	AudioOnly           bool              // only backup the audio of the lectures, tagged as a podcast
+	PathProfile         string            // file systems the file names must be valid on: posix, windows, exfat or ascii
+	Layout              string            // organization of the files: default, or show (for media servers)
+	CourseTemplate      string            // text/template of the course directory names (the course slug if empty)
+	ChapterTemplate     string            // text/template of the chapter directory names ("1. Title" if empty)
+	LectureTemplate     string            // text/template of the lecture file names ("1. Title" if empty)
+	Sync                bool              // move the files of renamed lectures instead of downloading them again
+	Archive             bool              // in sync mode, move the files of removed lectures into an ".archive" directory
+
+	// Courses overrides the options for some courses, by course ID or slug
+	Courses map[string]CourseOptions
+}
+
+// CourseOptions are the options that can be set per course, as in the config file:
+//
+//	courses:
+//	  my-course-slug:
+//	    resolution: 480
+//	    resolution-policy: at-most
+type CourseOptions struct {
+	PreferredResolution int               `mapstructure:"resolution"`
+	ResolutionPolicy    resolution.Policy `mapstructure:"resolution-policy"`
+}
+
+// New loads the configuration from viper (that is, from the command-line flags, the
+// config file or the UDEMY_* environment variables) and validates it.
+func New(ctx context.Context, c *client.Client) (*Config, error) {
+	cfg := &Config{
+		Client:              c,
+		RootDir:             viper.GetString("dir"),
+		PreferredResolution: viper.GetInt("resolution"),
+		ResolutionPolicy:    resolution.Policy(viper.GetString("resolution-policy")),
+		NumWorkers:          viper.GetInt("concurrency"),
+		Restart:             viper.GetBool("restart"),
+		LoadSubtitles:       viper.GetBool("subtitles"),
+		Captions: captions.Options{
+			Locales:   viper.GetStringSlice("subtitle-locales"),
+			Format:    captions.Format(viper.GetString("subtitle-format")),
+			Auto:      captions.AutoMode(viper.GetString("subtitle-auto")),
+			Strip:     viper.GetBool("subtitle-strip"),
+			Placement: captions.Placement(viper.GetString("subtitle-placement")),
+		},
+		AudioOnly:       viper.GetBool("audio-only"),
+		PathProfile:     viper.GetString("path-profile"),
+		Layout:          viper.GetString("layout"),
+		CourseTemplate:  viper.GetString("course-template"),
+		ChapterTemplate: viper.GetString("chapter-template"),
+		LectureTemplate: viper.GetString("lecture-template"),
+		Sync:            viper.GetBool("sync"),
+		Archive:         viper.GetBool("archive"),
+	}
+	if err := viper.UnmarshalKey("courses", &cfg.Courses); err != nil {
+		return nil, fmt.Errorf("config: invalid courses: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the options, and fills in the default values
+func (cfg *Config) Validate() error {
+	if cfg.Client == nil {
+		return errors.New("config: missing client")
+	}
+
+	// output directory
+	if cfg.RootDir == "" {
+		cfg.RootDir = "."
+	}
+	if strings.HasPrefix(cfg.RootDir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("config: could not expand dir %q: %v", cfg.RootDir, err)
+		}
+		cfg.RootDir = filepath.Join(home, cfg.RootDir[2:])
+	}
+	if s, err := os.Stat(cfg.RootDir); err == nil && !s.IsDir() {
+		return fmt.Errorf("config: dir %q is not a directory", cfg.RootDir)
+	}
+
+	if cfg.Archive && !cfg.Sync {
+		return errors.New("config: archive requires sync")
+	}
+
+	if err := cfg.Captions.Validate(); err != nil {
+		return fmt.Errorf("config: %v", err)
+	}
+
+	// naming templates (their fields are checked when they run)
+	for _, t := range [][2]string{
+		{"course-template", cfg.CourseTemplate},
+		{"chapter-template", cfg.ChapterTemplate},
+		{"lecture-template", cfg.LectureTemplate},
+	} {
+		if _, err := template.New(t[0]).Parse(t[1]); err != nil {
+			return fmt.Errorf("config: invalid %s: %v", t[0], err)
+		}
+	}
+
+	// downloads
+	if cfg.PreferredResolution < 0 {
+		return fmt.Errorf("config: invalid resolution %d", cfg.PreferredResolution)
+	}
+	if err := cfg.ResolutionPolicy.Validate(); err != nil {
+		return fmt.Errorf("config: %v", err)
+	}
+	for key, o := range cfg.Courses {
+		if o.PreferredResolution < 0 {
+			return fmt.Errorf("config: invalid resolution %d for course %s", o.PreferredResolution, key)
+		}
+		if err := o.ResolutionPolicy.Validate(); err != nil {
+			return fmt.Errorf("config: course %s: %v", key, err)
+		}
+	}
+	if cfg.NumWorkers < 0 {
+		return fmt.Errorf("config: invalid concurrency %d", cfg.NumWorkers)
+	} else if cfg.NumWorkers == 0 {
+		cfg.NumWorkers = runtime.NumCPU()
+	}
+	return nil
+}
+
+// ForCourse returns the configuration for the backup of a course, with the options set
+// for the course (by ID or slug) applied
+func (cfg *Config) ForCourse(course *client.Course) *Config {
+	o, ok := cfg.Courses[strconv.Itoa(course.ID)]
+	if !ok && course.PublishedTitle != "" {
+		o, ok = cfg.Courses[strings.ToLower(course.PublishedTitle)]
+	}
+	if !ok {
+		return cfg
+	}
+	c := *cfg
+	if o.PreferredResolution > 0 {
+		c.PreferredResolution = o.PreferredResolution
+	}
+	if o.ResolutionPolicy != "" {
+		c.ResolutionPolicy = o.ResolutionPolicy
+	}
+	return &c
+}
@@ -0,0 +1,16 @@
+package config
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a new context carrying cfg
+func NewContext(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, contextKey{}, cfg)
+}
+
+// FromContext returns the Config stored in ctx, if any
+func FromContext(ctx context.Context) (*Config, bool) {
+	cfg, ok := ctx.Value(contextKey{}).(*Config)
+	return cfg, ok
+}
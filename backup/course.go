@@ -0,0 +1,343 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ushu/udemy-backup/backup/config"
+	"github.com/ushu/udemy-backup/backup/pool"
+	"github.com/ushu/udemy-backup/client"
+)
+
+// DefaultRetryCount is the number of retries for failed downloads, when BackupCourse
+// runs its own worker pool
+const DefaultRetryCount = 2
+
+// EventType identifies the progress events sent during a backup
+type EventType int
+
+const (
+	// EventListed is sent once all the assets of the course have been listed
+	EventListed EventType = iota
+	// EventSkipped is sent for each asset that already exists locally
+	EventSkipped
+	// EventStarted is sent when the download of an asset starts
+	EventStarted
+	// EventDownloaded is sent when an asset has been successfully downloaded
+	EventDownloaded
+	// EventFailed is sent when an asset could not be downloaded (after all the retries)
+	EventFailed
+	// EventWarning is sent for non-fatal issues, as an unavailable resolution
+	EventWarning
+)
+
+// Event is a progress event sent during a backup
+type Event struct {
+	Type   EventType
+	Course *client.Course
+	Asset  Asset // not set for EventListed
+	Total  int   // number of listed assets, for EventListed
+	Err    error // for EventFailed and EventWarning
+}
+
+// EventHandler receives the progress events of a backup.
+// Calls to the handler are serialized by BackupCourse.
+type EventHandler func(e Event)
+
+type eventHandlerKey struct{}
+
+// WithEventHandler returns a new context carrying h: BackupCourse will report its
+// progress to h
+func WithEventHandler(ctx context.Context, h EventHandler) context.Context {
+	return context.WithValue(ctx, eventHandlerKey{}, h)
+}
+
+// Result lists the outcome of a backup for all the course assets
+type Result struct {
+	Course     *client.Course
+	Downloaded []Asset
+	Skipped    []Asset
+	Failed     []*AssetError
+	Moved      []Asset  // in sync mode, assets moved to their new path
+	Archived   []string // in sync mode, files moved to the archive (relative to the course directory)
+}
+
+// Err returns an error listing all the failed assets, or nil if none failed
+func (r *Result) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	errs := make(pool.Errors, len(r.Failed))
+	for i, f := range r.Failed {
+		errs[i] = f
+	}
+	return errs
+}
+
+// AssetError reports the failure to backup an asset
+type AssetError struct {
+	Asset Asset
+	Err   error
+}
+
+func (e *AssetError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Asset.LocalPath, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *AssetError) Unwrap() error {
+	return e.Err
+}
+
+// BackupCourse downloads all the assets of a course, as configured by the *config.Config
+// stored in ctx.
+//
+// The downloads are run by the *pool.Pool stored in ctx, if any (in that case the pool
+// must be started by the caller), or by a new pool of cfg.NumWorkers workers. The assets
+// derived from other files (like the PDF of slides) are built once the downloads are done,
+// and the ones listing the backup (like the player and the playlists) come last.
+// Progress is reported to the handler registered with WithEventHandler.
+func BackupCourse(ctx context.Context, course *client.Course) (*Result, error) {
+	cfg, ok := config.FromContext(ctx)
+	if !ok {
+		return nil, errors.New("backup: missing config in context")
+	}
+	cfg = cfg.ForCourse(course)
+	emit := eventEmitter(ctx)
+	res := &Result{Course: course}
+
+	// list all the available course elements
+	b := New(cfg.Client, cfg.RootDir, cfg.LoadSubtitles)
+	b.CourseDir = cfg.CourseDir
+	b.Resolution = cfg.PreferredResolution
+	b.Policy = cfg.ResolutionPolicy
+	b.Captions = cfg.Captions
+	b.AudioOnly = cfg.AudioOnly
+	if err := PathProfile(cfg.PathProfile).Validate(); err != nil {
+		return res, err
+	}
+	b.Paths = NewSanitizer(PathProfile(cfg.PathProfile))
+	if err := Layout(cfg.Layout).Validate(); err != nil {
+		return res, err
+	}
+	b.Layout = Layout(cfg.Layout)
+	naming, err := ParseNaming(cfg.CourseTemplate, cfg.ChapterTemplate, cfg.LectureTemplate)
+	if err != nil {
+		return res, err
+	}
+	b.Naming = naming
+	b.Warn = func(err error) {
+		emit(Event{Type: EventWarning, Course: course, Err: err})
+	}
+	allAssets, dirs, err := b.ListCourseAssets(ctx, course)
+	if err != nil {
+		return res, err
+	}
+	emit(Event{Type: EventListed, Course: course, Total: len(allAssets)})
+
+	// the manifest records what was backed up by previous runs
+	courseDir := b.getCourseDirectory(course)
+	manifest, err := b.LoadManifest(course)
+	if err != nil {
+		return res, err
+	}
+	manifest.Options = newManifestOptions(cfg)
+
+	// move the files of renamed lectures (before creating the new directories, since
+	// the directories left empty get removed)
+	if cfg.Sync {
+		sr, err := Sync(manifest, courseDir, allAssets, cfg.Archive)
+		if sr != nil {
+			res.Moved, res.Archived = sr.Moved, sr.Archived
+		}
+		if err != nil {
+			_ = b.SaveManifest(course, manifest)
+			return res, err
+		}
+	}
+
+	// create all the required directories
+	for _, d := range dirs {
+		if !dirExists(d) {
+			if err = os.MkdirAll(d, 0755); err != nil {
+				return res, err
+			}
+		}
+	}
+
+	// skip already-downloaded assets, unless we restart from scratch
+	var assets []Asset
+	for _, a := range allAssets {
+		if !cfg.Restart && !a.Refresh && isBackedUp(manifest, courseDir, a) {
+			res.Skipped = append(res.Skipped, a)
+			emit(Event{Type: EventSkipped, Course: course, Asset: a})
+			continue
+		}
+		assets = append(assets, a)
+	}
+
+	// run the downloads first, then build the assets derived from the downloaded files, and
+	// finally the ones listing the other files (like the player, which embeds the captions)
+	job := func(ctx context.Context, a Asset) error {
+		emit(Event{Type: EventStarted, Course: course, Asset: a})
+		if err := b.backupAsset(ctx, a); err != nil {
+			return err
+		}
+		if _, err := manifest.Record(courseDir, a); err != nil {
+			return err
+		}
+		emit(Event{Type: EventDownloaded, Course: course, Asset: a})
+		return nil
+	}
+	var downloads, derived, final []Asset
+	for _, a := range assets {
+		switch {
+		case a.Build != nil && a.Refresh:
+			final = append(final, a)
+		case a.Build != nil:
+			derived = append(derived, a)
+		default:
+			downloads = append(downloads, a)
+		}
+	}
+	for _, phase := range [][]Asset{downloads, derived, final} {
+		if len(phase) == 0 {
+			continue
+		}
+		errs := runAssetJobs(ctx, cfg, phase, job)
+		for i, a := range phase {
+			if err := errs[i]; err != nil {
+				res.Failed = append(res.Failed, &AssetError{a, err})
+				emit(Event{Type: EventFailed, Course: course, Asset: a, Err: err})
+			} else {
+				res.Downloaded = append(res.Downloaded, a)
+			}
+		}
+	}
+
+	// save the manifest, even after failures to keep track of the successful downloads
+	if err = b.SaveManifest(course, manifest); err != nil && len(res.Failed) == 0 {
+		return res, err
+	}
+	return res, res.Err()
+}
+
+// runAssetJobs runs fn for all the assets, on the shared pool if any, or on a new pool of
+// cfg.NumWorkers workers, and returns the error of each asset
+func runAssetJobs(ctx context.Context, cfg *config.Config, assets []Asset, fn func(context.Context, Asset) error) []error {
+	p, shared := pool.FromContext(ctx)
+	if !shared {
+		p = pool.New(cfg.NumWorkers)
+		p.RetryCount = DefaultRetryCount
+	}
+	jobs := make([]*pool.Job, 0, len(assets))
+	enqueue := func() {
+		for _, a := range assets {
+			a := a
+			name, _ := filepath.Rel(cfg.RootDir, a.LocalPath)
+			j, err := p.Enqueue(ctx, name, func(ctx context.Context) error {
+				return fn(ctx, a)
+			})
+			jobs = append(jobs, j)
+			if err != nil {
+				return // <- the pool won't take any more work
+			}
+		}
+	}
+	if shared {
+		enqueue()
+	} else {
+		enqueued := make(chan struct{})
+		go func() {
+			defer close(enqueued)
+			defer p.Done()
+			enqueue()
+		}()
+		_ = p.Start(ctx) // <- errors are collected from the jobs below
+		<-enqueued
+	}
+
+	errs := make([]error, len(assets))
+	for i := range assets {
+		if i < len(jobs) {
+			errs[i] = jobs[i].Wait()
+		} else if errs[i] = ctx.Err(); errs[i] == nil {
+			errs[i] = pool.ErrStopped
+		}
+	}
+	return errs
+}
+
+// isBackedUp checks the local file of an asset against the manifest
+func isBackedUp(m *Manifest, courseDir string, a Asset) bool {
+	s, err := os.Stat(a.LocalPath)
+	if err != nil || s.IsDir() {
+		return false
+	}
+	rel, err := filepath.Rel(courseDir, a.LocalPath)
+	if err != nil {
+		return false
+	}
+	if e := m.Lookup(rel); e != nil {
+		// truncated or replaced files get downloaded again, as the files of other
+		// lectures left at the path
+		return e.Key() == a.Key() && e.Size == s.Size()
+	}
+
+	// the file was downloaded by a version of the tool without manifest: adopt it
+	if s.Size() == 0 && a.RemoteURL != "" {
+		return false
+	}
+	_, err = m.Record(courseDir, a)
+	return err == nil
+}
+
+func (b *Backuper) backupAsset(ctx context.Context, a Asset) error {
+	if a.Build != nil {
+		return buildFile(ctx, a)
+	}
+	if a.HLS {
+		return b.hlsDownloader().DownloadFile(ctx, a.RemoteURL, a.LocalPath)
+	}
+	if a.RemoteURL != "" {
+		return downloadURLToFile(ctx, b.Client.HTTPClient, a.RemoteURL, a.LocalPath)
+	}
+	return ioutil.WriteFile(a.LocalPath, a.Contents, 0644)
+}
+
+// buildFile writes a derived asset, through a temporary file
+func buildFile(ctx context.Context, a Asset) error {
+	tmpPath := a.LocalPath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	err = a.Build(ctx, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, a.LocalPath)
+}
+
+// eventEmitter returns a function sending events to the handler stored in ctx (if any)
+func eventEmitter(ctx context.Context) func(Event) {
+	h, ok := ctx.Value(eventHandlerKey{}).(EventHandler)
+	if !ok || h == nil {
+		return func(Event) {}
+	}
+	var mu sync.Mutex
+	return func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		h(e)
+	}
+}
@@ -0,0 +1,152 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// resumeInfo is stored next to a partial ".tmp" file, to check that the remote file did
+// not change before resuming its download
+type resumeInfo struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// validator returns the value for the If-Range header
+func (i *resumeInfo) validator() string {
+	if i.ETag != "" {
+		return i.ETag
+	}
+	return i.LastModified
+}
+
+func downloadURLToFile(ctx context.Context, c *http.Client, url, filePath string) error {
+	tmpPath := filePath + ".tmp"
+	infoPath := tmpPath + ".resume"
+
+	// look for a previous partial download
+	var offset int64
+	info := loadResumeInfo(infoPath)
+	if info != nil {
+		if s, err := os.Stat(tmpPath); err == nil {
+			offset = s.Size()
+		}
+	}
+
+	// connect to the backend to get the file
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", info.validator())
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	// open file for writing
+	var f *os.File
+	switch {
+	case res.StatusCode == http.StatusPartialContent && offset > 0 && rangeStart(res) == offset:
+		// resume where we stopped
+		f, err = os.OpenFile(tmpPath, os.O_WRONLY|os.O_APPEND, 0644)
+	case res.StatusCode == http.StatusPartialContent:
+		// not the range we asked for
+		_ = os.Remove(tmpPath)
+		_ = os.Remove(infoPath)
+		return fmt.Errorf("could not resume download of %s: unexpected range %q", url, res.Header.Get("Content-Range"))
+	case res.StatusCode == http.StatusOK:
+		// (re)start from byte zero: either a new download, or the server ignored the range
+		f, err = os.Create(tmpPath)
+		if err == nil {
+			saveResumeInfo(infoPath, res)
+		}
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// the partial file does not match the remote one anymore: drop it and retry later
+		_ = os.Remove(tmpPath)
+		_ = os.Remove(infoPath)
+		return fmt.Errorf("could not resume download of %s: status=%d", url, res.StatusCode)
+	default:
+		return fmt.Errorf("failed to download %s: status=%d", url, res.StatusCode)
+	}
+	if err != nil {
+		return err
+	}
+
+	// load all the data into the local file
+	// (on failure the partial file is kept, so that the next attempt can resume it)
+	_, err = io.Copy(f, res.Body)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	// finally move the temp file into the final place
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(infoPath)
+	return os.Rename(tmpPath, filePath)
+}
+
+// loadResumeInfo returns the resume info of a partial download, or nil if it cannot be resumed
+func loadResumeInfo(infoPath string) *resumeInfo {
+	data, err := ioutil.ReadFile(infoPath)
+	if err != nil {
+		return nil
+	}
+	var info resumeInfo
+	if err := json.Unmarshal(data, &info); err != nil || info.validator() == "" {
+		return nil
+	}
+	return &info
+}
+
+// saveResumeInfo records how to resume the download, when the server supports it
+func saveResumeInfo(infoPath string, res *http.Response) {
+	info := resumeInfo{LastModified: res.Header.Get("Last-Modified")}
+	if etag := res.Header.Get("ETag"); !strings.HasPrefix(etag, "W/") {
+		info.ETag = etag // <- weak ETags cannot be used with If-Range
+	}
+	if res.Header.Get("Accept-Ranges") != "bytes" || info.validator() == "" {
+		_ = os.Remove(infoPath)
+		return
+	}
+	data, err := json.Marshal(info)
+	if err == nil {
+		_ = ioutil.WriteFile(infoPath, data, 0644)
+	}
+}
+
+// rangeStart returns the first byte position of a partial response, or -1
+func rangeStart(res *http.Response) int64 {
+	var start int64
+	if _, err := fmt.Sscanf(res.Header.Get("Content-Range"), "bytes %d-", &start); err != nil {
+		return -1
+	}
+	return start
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return !os.IsNotExist(err)
+}
+
+func dirExists(name string) bool {
+	s, err := os.Stat(name)
+	return !os.IsNotExist(err) && s.IsDir()
+}
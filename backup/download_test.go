@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDownloadURLToFileResume(t *testing.T) {
+	content := []byte("0123456789abcdefghij")
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content)) // <- handles Range and If-Range
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "file.bin")
+	tmpPath, infoPath := filePath+".tmp", filePath+".tmp.resume"
+
+	tests := []struct {
+		name      string
+		partial   string // <- contents of the ".tmp" file
+		etag      string // <- ETag of the resume info ("" for none)
+		wantRange string
+	}{
+		{"new download", "", "", ""},
+		{"resumed", "0123456789", `"v1"`, "bytes=10-"},
+		{"remote file changed", "XXXXXXXXXX", `"v0"`, "bytes=10-"}, // <- the server sends the whole file
+		{"no resume info", "XXXXXXXXXX", "", ""},
+	}
+	for _, tt := range tests {
+		ranges = nil
+		_ = os.Remove(filePath)
+		if tt.partial != "" {
+			if err := ioutil.WriteFile(tmpPath, []byte(tt.partial), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if tt.etag != "" {
+			data, _ := json.Marshal(resumeInfo{ETag: tt.etag})
+			if err := ioutil.WriteFile(infoPath, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := downloadURLToFile(context.Background(), srv.Client(), srv.URL, filePath); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(ranges) != 1 || ranges[0] != tt.wantRange {
+			t.Errorf("%s: Range headers %q, want %q", tt.name, ranges, tt.wantRange)
+		}
+		if got, _ := ioutil.ReadFile(filePath); !bytes.Equal(got, content) {
+			t.Errorf("%s: downloaded %q", tt.name, got)
+		}
+		if fileExists(tmpPath) || fileExists(infoPath) {
+			t.Errorf("%s: the temporary files are left", tt.name)
+		}
+	}
+}
+
+func TestDownloadURLToFileFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 3-9/20")
+		w.WriteHeader(http.StatusPartialContent)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "file.bin")
+	_ = ioutil.WriteFile(filePath+".tmp", []byte("0123456789"), 0644)
+	_ = ioutil.WriteFile(filePath+".tmp.resume", []byte(`{"etag":"\"v1\""}`), 0644)
+
+	// a range that does not match the partial file drops it
+	if err := downloadURLToFile(context.Background(), srv.Client(), srv.URL, filePath); err == nil {
+		t.Fatal("expected an error for an unexpected range")
+	}
+	if fileExists(filePath) || fileExists(filePath+".tmp") || fileExists(filePath+".tmp.resume") {
+		t.Error("the partial files should be removed")
+	}
+}
@@ -0,0 +1,154 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+// ListCodingExerciseAssets loads the coding problems of a "coding-exercise" quiz, and
+// rebuilds each of them as a directory tree:
+//
+//	README.md    instructions and hints
+//	starter/     the initial files
+//	solution/    the solution files
+//	tests/       the test files
+func (b *Backuper) ListCodingExerciseAssets(ctx context.Context, course *client.Course, quiz *client.Quiz) ([]Asset, []string, error) {
+	assessments, err := b.Client.LoadAllAssessments(ctx, quiz.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var exercises []*client.CodingExercise
+	for _, a := range assessments {
+		if e := a.CodingExercise(); e != nil {
+			exercises = append(exercises, e)
+		}
+	}
+
+	chapDir := b.getChapterDirectory(course, quiz.Chapter)
+	exerciseDir := filepath.Join(chapDir, b.getQuizPrefix(course, chapDir, quiz))
+	var assets []Asset
+	dirs := map[string]bool{exerciseDir: true}
+	for i, e := range exercises {
+		dir := exerciseDir
+		if len(exercises) > 1 {
+			// several problems: one sub-directory each
+			dir = filepath.Join(dir, b.paths().NameIn(b.RootDir, dir, fmt.Sprintf("%d. %s", i+1, e.Title), 0))
+			dirs[dir] = true
+		}
+		assets = append(assets, Asset{
+			LocalPath: filepath.Join(dir, "README.md"),
+			Contents:  exerciseToMarkdown(quiz, e),
+			Type:      AssetExercise,
+			AssetID:   e.ID,
+		})
+
+		trees := []struct {
+			name  string
+			files []*client.CodeFile
+		}{
+			{"starter", e.StarterFiles},
+			{"solution", e.SolutionFiles},
+			{"tests", e.TestFiles},
+		}
+		for _, t := range trees {
+			for _, f := range t.files {
+				name, ok := safeRelativePath(f.FileName)
+				if !ok {
+					continue
+				}
+				p := filepath.Join(dir, t.name, name)
+				dirs[filepath.Dir(p)] = true
+				assets = append(assets, Asset{
+					LocalPath: p,
+					Contents:  []byte(f.Content),
+					Type:      AssetExercise,
+					AssetID:   e.ID,
+					Variant:   t.name + "/" + filepath.ToSlash(name),
+				})
+			}
+		}
+	}
+
+	// tag all the assets with the quiz they belong to
+	for i := range assets {
+		assets[i].CourseID = course.ID
+		assets[i].LectureID = quiz.ID
+		if quiz.Chapter != nil {
+			assets[i].ChapterID = quiz.Chapter.ID
+		}
+	}
+
+	var directories []string
+	for d := range dirs {
+		directories = append(directories, d)
+	}
+	return assets, directories, nil
+}
+
+// ListPracticeAssets returns the Markdown export of the instructions of a practice activity
+func (b *Backuper) ListPracticeAssets(course *client.Course, practice *client.Practice) []Asset {
+	w := new(bytes.Buffer)
+	fmt.Fprintf(w, "# %s\n\n", practice.Title)
+	if practice.Description != "" {
+		fmt.Fprintf(w, "%s\n", strings.TrimSpace(fragmentToMarkdown(practice.Description)))
+	}
+
+	chapDir := b.getChapterDirectory(course, practice.Chapter)
+	a := Asset{
+		LocalPath: filepath.Join(chapDir, b.getPracticePrefix(course, chapDir, practice)+".practice.md"),
+		Contents:  w.Bytes(),
+		Type:      AssetPractice,
+		CourseID:  course.ID,
+		LectureID: practice.ID,
+	}
+	if practice.Chapter != nil {
+		a.ChapterID = practice.Chapter.ID
+	}
+	return []Asset{a}
+}
+
+func exerciseToMarkdown(quiz *client.Quiz, e *client.CodingExercise) []byte {
+	w := new(bytes.Buffer)
+	title := e.Title
+	if title == "" {
+		title = quiz.Title
+	}
+	fmt.Fprintf(w, "# %s\n\n", title)
+	if e.Instructions != "" {
+		fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(fragmentToMarkdown(e.Instructions)))
+	}
+	if len(e.Hints) > 0 {
+		fmt.Fprintf(w, "## Hints\n\n")
+		for _, h := range e.Hints {
+			fmt.Fprintf(w, "- %s\n", inlineMarkdown(h))
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "## Files\n\n")
+	if len(e.StarterFiles) > 0 {
+		fmt.Fprintf(w, "- `starter/`: the initial files\n")
+	}
+	if len(e.SolutionFiles) > 0 {
+		fmt.Fprintf(w, "- `solution/`: the solution\n")
+	}
+	if len(e.TestFiles) > 0 {
+		fmt.Fprintf(w, "- `tests/`: the tests\n")
+	}
+	return w.Bytes()
+}
+
+// safeRelativePath cleans a file name coming from the API, so that it cannot escape its
+// directory
+func safeRelativePath(name string) (string, bool) {
+	p := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+	if p == "" {
+		return "", false
+	}
+	return filepath.FromSlash(p), true
+}
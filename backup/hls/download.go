@@ -0,0 +1,241 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/ushu/udemy-backup/backup/resolution"
+)
+
+// DefaultConcurrency is the default number of segments downloaded in parallel
+const DefaultConcurrency = 4
+
+// Downloader fetches HLS streams and joins their segments into a single file
+type Downloader struct {
+	Client      *http.Client
+	Resolution  int               // preferred resolution (height), 0 means "highest"
+	Policy      resolution.Policy // selection of the resolution among the variants
+	Concurrency int               // number of segments downloaded in parallel
+
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+// NewDownloader creates a Downloader using the given HTTP client
+func NewDownloader(c *http.Client, resolution int) *Downloader {
+	return &Downloader{
+		Client:      c,
+		Resolution:  resolution,
+		Concurrency: DefaultConcurrency,
+	}
+}
+
+// DownloadFile downloads the stream into filePath, through a temporary file (removed on
+// failure)
+func (d *Downloader) DownloadFile(ctx context.Context, playlistURL, filePath string) error {
+	tmpPath := filePath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	err = d.Download(ctx, playlistURL, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, filePath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+	}
+	return err
+}
+
+// Download loads the playlist (selecting the best variant of a master playlist), then
+// writes all the decrypted segments in order into w
+func (d *Downloader) Download(ctx context.Context, playlistURL string, w io.Writer) error {
+	media, err := d.LoadMediaPlaylist(ctx, playlistURL)
+	if err != nil {
+		return err
+	}
+	if len(media.Segments) == 0 {
+		return errors.New("hls: no segments in playlist")
+	}
+
+	// fragmented MP4 streams start with an initialization segment
+	if media.Map != "" {
+		data, err := d.get(ctx, media.Map)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// segments are downloaded in parallel, but written in order: each segment gets its own
+	// result channel, and the semaphore limits the number of segments kept in memory
+	type result struct {
+		data []byte
+		err  error
+	}
+	concurrency := d.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	results := make([]chan result, len(media.Segments))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	go func() {
+		for i, s := range media.Segments {
+			select {
+			case <-ctx.Done():
+				results[i] <- result{err: ctx.Err()}
+				continue
+			case sem <- struct{}{}:
+			}
+			go func(i int, s *Segment) {
+				data, err := d.fetchSegment(ctx, s)
+				results[i] <- result{data, err}
+			}(i, s)
+		}
+	}()
+
+	for i := range media.Segments {
+		r := <-results[i]
+		<-sem
+		if r.err != nil {
+			return fmt.Errorf("hls: segment %d: %v", i, r.err)
+		}
+		if _, err := w.Write(r.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadMediaPlaylist loads the playlist at playlistURL, following the best variant when
+// it is a master playlist
+func (d *Downloader) LoadMediaPlaylist(ctx context.Context, playlistURL string) (*MediaPlaylist, error) {
+	for depth := 0; depth < 2; depth++ {
+		base, err := url.Parse(playlistURL)
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.get(ctx, playlistURL)
+		if err != nil {
+			return nil, err
+		}
+		master, media, err := Parse(bytes.NewReader(data), base)
+		if err != nil {
+			return nil, err
+		}
+		if media != nil {
+			return media, nil
+		}
+		v := master.SelectVariant(d.Resolution, d.Policy)
+		if v == nil {
+			return nil, fmt.Errorf("hls: no variant at %dp", d.Resolution)
+		}
+		playlistURL = v.URI
+	}
+	return nil, errors.New("hls: nested master playlists")
+}
+
+func (d *Downloader) fetchSegment(ctx context.Context, s *Segment) ([]byte, error) {
+	data, err := d.get(ctx, s.URI)
+	if err != nil || s.Key == nil {
+		return data, err
+	}
+	key, err := d.key(ctx, s.Key.URI)
+	if err != nil {
+		return nil, err
+	}
+	iv := s.Key.IV
+	if iv == nil {
+		// the IV defaults to the sequence number, as a 128-bit big-endian integer
+		iv = make([]byte, aes.BlockSize)
+		binary.BigEndian.PutUint64(iv[8:], uint64(s.Sequence))
+	}
+	return decrypt(data, key, iv)
+}
+
+// key returns the (cached) AES key at keyURL
+func (d *Downloader) key(ctx context.Context, keyURL string) ([]byte, error) {
+	d.mu.Lock()
+	key, ok := d.keys[keyURL]
+	d.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	key, err := d.get(ctx, keyURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 16 {
+		return nil, fmt.Errorf("hls: invalid AES-128 key length %d", len(key))
+	}
+	d.mu.Lock()
+	if d.keys == nil {
+		d.keys = make(map[string][]byte)
+	}
+	d.keys[keyURL] = key
+	d.mu.Unlock()
+	return key, nil
+}
+
+func (d *Downloader) get(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	c := d.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	res, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("hls: GET %s: status=%d", u, res.StatusCode)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// decrypt decrypts AES-128-CBC data, and removes the PKCS#7 padding
+func decrypt(data, key, iv []byte) ([]byte, error) {
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("hls: encrypted segment is not a multiple of the block size")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, data)
+	pad := int(data[len(data)-1])
+	if pad == 0 || pad > aes.BlockSize || pad > len(data) {
+		return nil, errors.New("hls: invalid padding")
+	}
+	return data[:len(data)-pad], nil
+}
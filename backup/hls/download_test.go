@@ -0,0 +1,89 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// encrypt encrypts data with AES-128-CBC, with the PKCS#7 padding
+func encrypt(t *testing.T, data, key, iv []byte) []byte {
+	pad := aes.BlockSize - len(data)%aes.BlockSize
+	data = append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	return data
+}
+
+func TestDecrypt(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	iv := make([]byte, aes.BlockSize)
+	for _, plain := range []string{"", "segment", "exactly 16 bytes"} {
+		got, err := decrypt(encrypt(t, []byte(plain), key, iv), key, iv)
+		if err != nil || string(got) != plain {
+			t.Errorf("decrypt() = %q, %v, want %q", got, err, plain)
+		}
+	}
+	if _, err := decrypt([]byte("not a block"), key, iv); err == nil {
+		t.Error("decrypt() should fail on partial blocks")
+	}
+}
+
+func TestDownload(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(iv[8:], 1) // <- the sequence number of the encrypted segment
+	files := map[string]string{
+		"/master.m3u8": "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1,RESOLUTION=640x360\nlow.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=2,RESOLUTION=1280x720\nhigh.m3u8\n",
+		"/high.m3u8":   "#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXTINF:1,\na.ts\n#EXT-X-KEY:METHOD=AES-128,URI=\"key\"\n#EXTINF:1,\nb.ts\n#EXTINF:1,\nc.ts\n",
+		"/a.ts":        "first,",
+		"/b.ts":        string(encrypt(t, []byte("second,"), key, iv)),
+		"/key":         string(key),
+	}
+	binary.BigEndian.PutUint64(iv[8:], 2)
+	files["/c.ts"] = string(encrypt(t, []byte("third"), key, iv))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(data))
+	}))
+	defer srv.Close()
+
+	d := NewDownloader(srv.Client(), 0)
+	var buf bytes.Buffer
+	if err := d.Download(context.Background(), srv.URL+"/master.m3u8", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "first,second,third" {
+		t.Errorf("Download() wrote %q", got)
+	}
+
+	// the failed downloads leave no file behind
+	dir, err := ioutil.TempDir("", "hls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	delete(files, "/c.ts")
+	filePath := filepath.Join(dir, "video.ts")
+	if err := d.DownloadFile(context.Background(), srv.URL+"/high.m3u8", filePath); err == nil {
+		t.Fatal("DownloadFile() should fail on a missing segment")
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("%s is left after the failure", entries[0].Name())
+	}
+}
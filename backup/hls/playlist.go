@@ -0,0 +1,280 @@
+package hls
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ushu/udemy-backup/backup/resolution"
+)
+
+// MimeTypes lists the content types used for HLS playlists
+var MimeTypes = []string{
+	"application/x-mpegurl",
+	"application/vnd.apple.mpegurl",
+	"audio/mpegurl",
+}
+
+// IsPlaylist reports whether the given content type is an HLS playlist
+func IsPlaylist(mimeType string) bool {
+	mimeType = strings.ToLower(mimeType)
+	for _, t := range MimeTypes {
+		if mimeType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// MasterPlaylist lists the available renditions of a stream
+type MasterPlaylist struct {
+	Variants []*Variant
+}
+
+// Variant is a rendition of the stream, pointing to a media playlist
+type Variant struct {
+	URI       string
+	Bandwidth int
+	Width     int
+	Height    int
+	Codecs    string
+}
+
+// MediaPlaylist lists the segments of a rendition
+type MediaPlaylist struct {
+	TargetDuration float64
+	MediaSequence  int
+	Map            string // URI of the initialization segment (fragmented MP4 streams)
+	Segments       []*Segment
+}
+
+// Segment is a chunk of the stream
+type Segment struct {
+	URI      string
+	Duration float64
+	Sequence int
+	Key      *Key // nil when the segment is not encrypted
+}
+
+// Key describes the encryption of a segment
+type Key struct {
+	Method string // only "AES-128" is supported
+	URI    string
+	IV     []byte // nil means "use the sequence number"
+}
+
+// Ext returns the file extension matching the container of the segments
+func (m *MediaPlaylist) Ext() string {
+	if m.Map != "" {
+		return ".mp4"
+	}
+	return ".ts"
+}
+
+// SelectVariant returns the variant whose height is selected by the resolution policy
+// (preferring the highest bandwidth on ties), or nil if none matches
+func (m *MasterPlaylist) SelectVariant(preferred int, policy resolution.Policy) *Variant {
+	variants := make([]*Variant, len(m.Variants))
+	copy(variants, m.Variants)
+	sort.SliceStable(variants, func(i, j int) bool {
+		return variants[i].Bandwidth > variants[j].Bandwidth
+	})
+	heights := make([]int, len(variants))
+	for i, v := range variants {
+		heights[i] = v.Height
+	}
+	if i, _ := resolution.Select(heights, preferred, policy); i >= 0 {
+		return variants[i]
+	}
+	return nil
+}
+
+// Parse reads a master or a media playlist (exactly one of the returned playlists is
+// non-nil). Relative URIs are resolved against base.
+// The streams that cannot be joined into a single file (byte range segments, or audio in
+// separate renditions) are rejected with an "unsupported" error.
+func Parse(r io.Reader, base *url.URL) (*MasterPlaylist, *MediaPlaylist, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	if !s.Scan() || strings.TrimSpace(s.Text()) != "#EXTM3U" {
+		if err := s.Err(); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("hls: missing #EXTM3U header")
+	}
+
+	var (
+		master  MasterPlaylist
+		media   MediaPlaylist
+		isMedia bool
+
+		variant  *Variant // pending #EXT-X-STREAM-INF
+		duration float64  // pending #EXTINF
+		key      *Key
+	)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case line == "":
+			continue
+
+		// master playlist
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			attrs := parseAttributes(line[len("#EXT-X-STREAM-INF:"):])
+			variant = &Variant{Codecs: attrs["CODECS"]}
+			variant.Bandwidth, _ = strconv.Atoi(attrs["BANDWIDTH"])
+			if res := strings.SplitN(attrs["RESOLUTION"], "x", 2); len(res) == 2 {
+				variant.Width, _ = strconv.Atoi(res[0])
+				variant.Height, _ = strconv.Atoi(res[1])
+			}
+
+		case strings.HasPrefix(line, "#EXT-X-MEDIA:"):
+			attrs := parseAttributes(line[len("#EXT-X-MEDIA:"):])
+			if attrs["TYPE"] == "AUDIO" && attrs["URI"] != "" {
+				return nil, nil, errors.New("hls: unsupported separate audio renditions")
+			}
+
+		// media playlist
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+			isMedia = true
+			media.TargetDuration, _ = strconv.ParseFloat(line[len("#EXT-X-TARGETDURATION:"):], 64)
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			isMedia = true
+			media.MediaSequence, _ = strconv.Atoi(line[len("#EXT-X-MEDIA-SEQUENCE:"):])
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			attrs := parseAttributes(line[len("#EXT-X-MAP:"):])
+			u, err := resolve(base, attrs["URI"])
+			if err != nil {
+				return nil, nil, err
+			}
+			media.Map = u
+		case strings.HasPrefix(line, "#EXT-X-KEY:"):
+			k, err := parseKey(line[len("#EXT-X-KEY:"):], base)
+			if err != nil {
+				return nil, nil, err
+			}
+			key = k
+		case strings.HasPrefix(line, "#EXT-X-BYTERANGE:"):
+			return nil, nil, errors.New("hls: unsupported byte range segments")
+		case strings.HasPrefix(line, "#EXTINF:"):
+			isMedia = true
+			d := strings.SplitN(line[len("#EXTINF:"):], ",", 2)[0]
+			duration, _ = strconv.ParseFloat(d, 64)
+
+		case strings.HasPrefix(line, "#"):
+			// comment or unsupported tag
+
+		// URI lines
+		default:
+			u, err := resolve(base, line)
+			if err != nil {
+				return nil, nil, err
+			}
+			if variant != nil {
+				variant.URI = u
+				master.Variants = append(master.Variants, variant)
+				variant = nil
+			} else {
+				media.Segments = append(media.Segments, &Segment{
+					URI:      u,
+					Duration: duration,
+					Sequence: media.MediaSequence + len(media.Segments),
+					Key:      key,
+				})
+				duration = 0
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(master.Variants) > 0 {
+		return &master, nil, nil
+	}
+	if isMedia || len(media.Segments) > 0 {
+		return nil, &media, nil
+	}
+	return nil, nil, errors.New("hls: empty playlist")
+}
+
+func parseKey(s string, base *url.URL) (*Key, error) {
+	attrs := parseAttributes(s)
+	k := &Key{Method: attrs["METHOD"]}
+	switch k.Method {
+	case "NONE":
+		return nil, nil
+	case "AES-128":
+	default:
+		return nil, fmt.Errorf("hls: unsupported encryption method %q", k.Method)
+	}
+	u, err := resolve(base, attrs["URI"])
+	if err != nil {
+		return nil, err
+	}
+	k.URI = u
+	if iv := attrs["IV"]; iv != "" {
+		iv = strings.TrimPrefix(strings.TrimPrefix(iv, "0x"), "0X")
+		if k.IV, err = hex.DecodeString(iv); err != nil || len(k.IV) != 16 {
+			return nil, fmt.Errorf("hls: invalid IV %q", attrs["IV"])
+		}
+	}
+	return k, nil
+}
+
+// parseAttributes parses attribute lists as in: BANDWIDTH=1280000,CODECS="avc1.4d401f,mp4a.40.2"
+func parseAttributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	for len(s) > 0 {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		name := strings.TrimSpace(s[:eq])
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else if comma := strings.IndexByte(s, ','); comma >= 0 {
+			value, s = s[:comma], s[comma:]
+		} else {
+			value, s = s, ""
+		}
+		attrs[name] = value
+		s = strings.TrimPrefix(s, ",")
+	}
+	return attrs
+}
+
+func resolve(base *url.URL, ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if base == nil {
+		return u.String(), nil
+	}
+	return base.ResolveReference(u).String(), nil
+}
+
+// Duration returns the total duration of the segments, in seconds
+func (m *MediaPlaylist) Duration() float64 {
+	var d float64
+	for _, s := range m.Segments {
+		d += s.Duration
+	}
+	return math.Round(d*1000) / 1000
+}
@@ -0,0 +1,110 @@
+package hls
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseMaster(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/video/master.m3u8?token=1")
+	playlist := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360,CODECS="avc1.4d401e,mp4a.40.2"
+360/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2500000,RESOLUTION=1280x720
+https://other.example.com/720.m3u8
+`
+	master, media, err := Parse(strings.NewReader(playlist), base)
+	if err != nil || media != nil || master == nil {
+		t.Fatalf("Parse() = %v, %v, %v", master, media, err)
+	}
+	want := []Variant{
+		{URI: "https://cdn.example.com/video/360/index.m3u8", Bandwidth: 800000, Width: 640, Height: 360, Codecs: "avc1.4d401e,mp4a.40.2"},
+		{URI: "https://other.example.com/720.m3u8", Bandwidth: 2500000, Width: 1280, Height: 720},
+	}
+	if len(master.Variants) != len(want) {
+		t.Fatalf("%d variants, want %d", len(master.Variants), len(want))
+	}
+	for i, v := range master.Variants {
+		if *v != want[i] {
+			t.Errorf("variant %d = %+v, want %+v", i, *v, want[i])
+		}
+	}
+	if v := master.SelectVariant(480, "closest"); v == nil || v.Height != 360 {
+		t.Errorf("SelectVariant(480) = %+v, want the 360p variant", v)
+	}
+}
+
+func TestParseMedia(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/video/720/index.m3u8")
+	playlist := `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXT-X-MEDIA-SEQUENCE:5
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:10.0,
+seg5.m4s
+#EXT-X-KEY:METHOD=AES-128,URI="/keys/1",IV=0x000102030405060708090a0b0c0d0e0f
+#EXTINF:9.5,title
+seg6.m4s
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:2.25,
+seg7.m4s
+#EXT-X-ENDLIST
+`
+	master, media, err := Parse(strings.NewReader(playlist), base)
+	if err != nil || master != nil || media == nil {
+		t.Fatalf("Parse() = %v, %v, %v", master, media, err)
+	}
+	if media.Map != "https://cdn.example.com/video/720/init.mp4" || media.Ext() != ".mp4" {
+		t.Errorf("Map = %q, Ext() = %q", media.Map, media.Ext())
+	}
+	if len(media.Segments) != 3 {
+		t.Fatalf("%d segments, want 3", len(media.Segments))
+	}
+	if d := media.Duration(); d != 21.75 {
+		t.Errorf("Duration() = %v, want 21.75", d)
+	}
+	for i, s := range media.Segments {
+		if s.Sequence != 5+i {
+			t.Errorf("segment %d: sequence %d, want %d", i, s.Sequence, 5+i)
+		}
+		if encrypted := s.Key != nil; encrypted != (i == 1) {
+			t.Errorf("segment %d: key %+v", i, s.Key)
+		}
+	}
+	k := media.Segments[1].Key
+	if k.URI != "https://cdn.example.com/keys/1" || len(k.IV) != 16 || k.IV[15] != 0x0f {
+		t.Errorf("key = %+v", k)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		playlist string
+		want     string
+	}{
+		{"no header", "#EXTINF:1,\nseg.ts\n", "missing #EXTM3U"},
+		{"empty", "#EXTM3U\n", "empty playlist"},
+		{"byte range", "#EXTM3U\n#EXTINF:1,\n#EXT-X-BYTERANGE:1000@0\nseg.ts\n", "unsupported byte range"},
+		{"audio rendition", "#EXTM3U\n#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"a\",URI=\"audio.m3u8\"\n", "unsupported separate audio"},
+		{"encryption", "#EXTM3U\n#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"k\"\n", "unsupported encryption"},
+		{"IV", "#EXTM3U\n#EXT-X-KEY:METHOD=AES-128,URI=\"k\",IV=0x01\n", "invalid IV"},
+	}
+	for _, tt := range tests {
+		_, _, err := Parse(strings.NewReader(tt.playlist), nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Parse() = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	attrs := parseAttributes(`BANDWIDTH=1280000,CODECS="avc1.4d401f,mp4a.40.2",RESOLUTION=1280x720`)
+	want := map[string]string{"BANDWIDTH": "1280000", "CODECS": "avc1.4d401f,mp4a.40.2", "RESOLUTION": "1280x720"}
+	for k, v := range want {
+		if attrs[k] != v {
+			t.Errorf("%s = %q, want %q", k, attrs[k], v)
+		}
+	}
+}
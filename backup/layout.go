@@ -0,0 +1,243 @@
+package backup
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/ushu/udemy-backup/backup/resolution"
+	"github.com/ushu/udemy-backup/client"
+)
+
+// Layout is the organization of the backed up files
+type Layout string
+
+const (
+	// DefaultLayout has numbered chapter directories and lecture files
+	DefaultLayout Layout = "default"
+	// ShowLayout organizes the course as a TV show for the media servers (as Plex or
+	// Jellyfin): the chapters are seasons, and the lectures are episodes ("S01E03 - Title"),
+	// described by NFO files
+	ShowLayout Layout = "show"
+)
+
+// Validate checks the layout (empty means DefaultLayout)
+func (l Layout) Validate() error {
+	switch l {
+	case "", DefaultLayout, ShowLayout:
+		return nil
+	}
+	return fmt.Errorf("backup: invalid layout %q", l)
+}
+
+// showNaming holds the names of the show layout (the user templates take precedence)
+var showNaming = &Naming{
+	Course:  template.Must(template.New("course").Parse(`{{.Course.Title}}`)),
+	Chapter: template.Must(template.New("chapter").Parse(`Season {{printf "%02d" .Season}}`)),
+	Lecture: template.Must(template.New("lecture").Parse(`S{{printf "%02d" .Season}}E{{printf "%02d" .Episode}} - {{.Lecture.Title}}`)),
+}
+
+// episodeNumbers numbers the lectures giving a media file in each season (the items
+// before the first chapter are in season 0), so that the episodes have no gaps. The other
+// items get the number of the previous episode, for their files to sort next to it.
+func (b *Backuper) episodeNumbers(curriculum []interface{}) map[string]int {
+	episodes := make(map[string]int)
+	counts := make(map[int]int)
+	season := 0
+	for _, item := range curriculum {
+		var key string
+		switch i := item.(type) {
+		case *client.Chapter:
+			season = seasonNumber(i)
+			continue
+		case *client.Lecture:
+			key = episodeKey("lecture", i.ID)
+			if b.hasMedia(i) {
+				counts[season]++
+			}
+		case *client.Quiz:
+			key = episodeKey("quiz", i.ID)
+		case *client.Practice:
+			key = episodeKey("practice", i.ID)
+		default:
+			continue
+		}
+		episodes[key] = counts[season]
+	}
+	return episodes
+}
+
+func episodeKey(kind string, id int) string {
+	return kind + "/" + strconv.Itoa(id)
+}
+
+// seasonNumber returns the season of a chapter: 0 (the specials of the media servers)
+// for the items before the first chapter
+func seasonNumber(chapter *client.Chapter) int {
+	if chapter == nil {
+		return 0
+	} else if chapter.ObjectIndex <= 0 {
+		return 1
+	}
+	return chapter.ObjectIndex
+}
+
+// NFO files, as read by Kodi, Jellyfin and the XBMCnfo agents of Plex
+
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr"`
+	ID      int    `xml:",chardata"`
+}
+
+type nfoActor struct {
+	Name  string `xml:"name"`
+	Role  string `xml:"role,omitempty"`
+	Thumb string `xml:"thumb,omitempty"`
+}
+
+type tvShowNFO struct {
+	XMLName  xml.Name    `xml:"tvshow"`
+	Title    string      `xml:"title"`
+	Outline  string      `xml:"outline,omitempty"`
+	Plot     string      `xml:"plot,omitempty"`
+	Studio   string      `xml:"studio"`
+	Premiere string      `xml:"premiered,omitempty"`
+	UniqueID nfoUniqueID `xml:"uniqueid"`
+	Actors   []nfoActor  `xml:"actor"`
+}
+
+type seasonNFO struct {
+	XMLName      xml.Name `xml:"season"`
+	Title        string   `xml:"title"`
+	SeasonNumber int      `xml:"seasonnumber"`
+}
+
+type episodeNFO struct {
+	XMLName   xml.Name    `xml:"episodedetails"`
+	Title     string      `xml:"title"`
+	ShowTitle string      `xml:"showtitle"`
+	Season    int         `xml:"season"`
+	Episode   int         `xml:"episode"`
+	UniqueID  nfoUniqueID `xml:"uniqueid"`
+}
+
+// listShowAssets returns the NFO files of the show layout: "tvshow.nfo" at the course
+// root, a "season.nfo" file per chapter, and a NFO file next to each lecture video (or
+// audio file)
+func (b *Backuper) listShowAssets(course, details *client.Course, curriculum []interface{}) ([]Asset, error) {
+	if details == nil {
+		details = course
+	}
+	show := &tvShowNFO{
+		Title:    course.Title,
+		Outline:  details.Headline,
+		Plot:     strings.TrimSpace(fragmentToMarkdown(details.Description)),
+		Studio:   "Udemy",
+		Premiere: details.LastUpdateDate,
+		UniqueID: nfoUniqueID{Type: "udemy", Default: true, ID: course.ID},
+	}
+	for _, u := range details.Instructors {
+		name := u.DisplayName
+		if name == "" {
+			name = u.Title
+		}
+		show.Actors = append(show.Actors, nfoActor{Name: name, Role: u.JobTitle, Thumb: u.Image})
+	}
+
+	var assets []Asset
+	courseDir := b.getCourseDirectory(course)
+	add := func(path string, v interface{}, lectureID int) error {
+		data, err := xml.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		a := Asset{
+			LocalPath: path,
+			Contents:  append([]byte(xml.Header), append(data, '\n')...),
+			Type:      AssetCourse,
+			CourseID:  course.ID,
+			LectureID: lectureID,
+		}
+		if lectureID == 0 {
+			a.Variant = courseVariant(courseDir, path) // <- tvshow.nfo and the season.nfo files
+		}
+		assets = append(assets, a)
+		return nil
+	}
+	if err := add(filepath.Join(courseDir, "tvshow.nfo"), show, 0); err != nil {
+		return nil, err
+	}
+	for _, item := range curriculum {
+		switch i := item.(type) {
+		case *client.Chapter:
+			season := &seasonNFO{Title: i.Title, SeasonNumber: seasonNumber(i)}
+			if err := add(filepath.Join(b.getChapterDirectory(course, i), "season.nfo"), season, 0); err != nil {
+				return nil, err
+			}
+		case *client.Lecture:
+			if !b.hasMedia(i) {
+				continue // <- not an episode
+			}
+			chapDir := b.getChapterDirectory(course, i.Chapter)
+			episode := &episodeNFO{
+				Title:     i.Title,
+				ShowTitle: course.Title,
+				Season:    seasonNumber(i.Chapter),
+				Episode:   b.episode("lecture", i.ID, i.ObjectIndex),
+				UniqueID:  nfoUniqueID{Type: "udemy", Default: true, ID: i.ID},
+			}
+			prefix := b.getLecturePrefix(course, chapDir, i, b.lectureVideo(i))
+			if err := add(filepath.Join(chapDir, prefix+".nfo"), episode, i.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return assets, nil
+}
+
+// courseVariant returns the variant of a course file, as its path relative to the course
+// directory, so that the files of a same name (as the "season.nfo" files) get distinct keys
+func courseVariant(courseDir, path string) string {
+	if rel, err := filepath.Rel(courseDir, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
+
+// episode returns the episode number of a curriculum item, or fallback if the curriculum
+// was not numbered
+func (b *Backuper) episode(kind string, id, fallback int) int {
+	if n, ok := b.episodes[episodeKey(kind, id)]; ok {
+		return n
+	}
+	return fallback
+}
+
+// hasMedia reports whether a lecture gives a video or an audio file
+func (b *Backuper) hasMedia(lecture *client.Lecture) bool {
+	if lecture.Asset == nil {
+		return false
+	}
+	videos := findVideos(lecture)
+	if findAudio(lecture, videos) != nil {
+		return true
+	}
+	if b.AudioOnly {
+		video, _ := filterVideos(videos, 0, resolution.Lowest) // <- as in listPodcastAssets
+		return video != nil && video.Type == "video/mp4"
+	}
+	return b.lectureVideo(lecture) != nil || findPlaylist(videos) != nil
+}
+
+// lectureVideo returns the video selected for the lecture, if any
+func (b *Backuper) lectureVideo(lecture *client.Lecture) *client.Video {
+	if lecture.Asset == nil {
+		return nil
+	}
+	video, _ := filterVideos(findVideos(lecture), b.Resolution, b.Policy)
+	return video
+}
@@ -0,0 +1,34 @@
+package backup
+
+import (
+	"testing"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+func TestListShowAssetsKeys(t *testing.T) {
+	b := &Backuper{RootDir: "backups", CourseDir: "backups/course", Layout: ShowLayout}
+	course := &client.Course{ID: 1, Title: "Course"}
+	curriculum := []interface{}{
+		&client.Chapter{ID: 10, ObjectIndex: 1, Title: "Basics"},
+		&client.Chapter{ID: 11, ObjectIndex: 2, Title: "Advanced"},
+	}
+	assets, err := b.listShowAssets(course, nil, curriculum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 3 {
+		t.Fatalf("%d assets, want tvshow.nfo and 2 season.nfo", len(assets))
+	}
+	keys := make(map[string]string)
+	for _, a := range assets {
+		k := a.Key()
+		if p, ok := keys[k]; ok {
+			t.Errorf("%q and %q share the key %q", p, a.LocalPath, k)
+		}
+		keys[k] = a.LocalPath
+	}
+	if v := assets[1].Variant; v != "Season 01/season.nfo" {
+		t.Errorf("variant = %q, want %q", v, "Season 01/season.nfo")
+	}
+}
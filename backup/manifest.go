@@ -0,0 +1,254 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ushu/udemy-backup/backup/captions"
+	"github.com/ushu/udemy-backup/backup/config"
+	"github.com/ushu/udemy-backup/backup/resolution"
+	"github.com/ushu/udemy-backup/client"
+)
+
+// ManifestFileName is the name of the manifest, written at the root of each course directory
+const ManifestFileName = ".udemy-backup.json"
+
+// ManifestVersion is the version of the manifest format
+const ManifestVersion = 1
+
+// Manifest records all the assets backed up for a course
+type Manifest struct {
+	Version     int              `json:"version"`
+	CourseID    int              `json:"course_id"`
+	CourseTitle string           `json:"course_title"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Options     *ManifestOptions `json:"options,omitempty"` // nil for the backups of previous versions
+	Entries     []*ManifestEntry `json:"assets"`
+
+	mu sync.Mutex
+}
+
+// ManifestOptions are the options of the last backup that shape the files of the course,
+// so that the course can be repaired with the same names and formats
+type ManifestOptions struct {
+	Resolution        int                `json:"resolution,omitempty"`
+	ResolutionPolicy  resolution.Policy  `json:"resolution_policy,omitempty"`
+	Subtitles         bool               `json:"subtitles,omitempty"`
+	SubtitleLocales   []string           `json:"subtitle_locales,omitempty"`
+	SubtitleFormat    captions.Format    `json:"subtitle_format,omitempty"`
+	SubtitleAuto      captions.AutoMode  `json:"subtitle_auto,omitempty"`
+	SubtitleStrip     bool               `json:"subtitle_strip,omitempty"`
+	SubtitlePlacement captions.Placement `json:"subtitle_placement,omitempty"`
+	AudioOnly         bool               `json:"audio_only,omitempty"`
+	PathProfile       string             `json:"path_profile,omitempty"`
+	Layout            string             `json:"layout,omitempty"`
+	CourseTemplate    string             `json:"course_template,omitempty"`
+	ChapterTemplate   string             `json:"chapter_template,omitempty"`
+	LectureTemplate   string             `json:"lecture_template,omitempty"`
+}
+
+func newManifestOptions(cfg *config.Config) *ManifestOptions {
+	return &ManifestOptions{
+		Resolution:        cfg.PreferredResolution,
+		ResolutionPolicy:  cfg.ResolutionPolicy,
+		Subtitles:         cfg.LoadSubtitles,
+		SubtitleLocales:   cfg.Captions.Locales,
+		SubtitleFormat:    cfg.Captions.Format,
+		SubtitleAuto:      cfg.Captions.Auto,
+		SubtitleStrip:     cfg.Captions.Strip,
+		SubtitlePlacement: cfg.Captions.Placement,
+		AudioOnly:         cfg.AudioOnly,
+		PathProfile:       cfg.PathProfile,
+		Layout:            cfg.Layout,
+		CourseTemplate:    cfg.CourseTemplate,
+		ChapterTemplate:   cfg.ChapterTemplate,
+		LectureTemplate:   cfg.LectureTemplate,
+	}
+}
+
+// Apply sets the options in cfg. The options set per course are dropped, since the
+// recorded ones already include them.
+func (o *ManifestOptions) Apply(cfg *config.Config) {
+	cfg.PreferredResolution = o.Resolution
+	cfg.ResolutionPolicy = o.ResolutionPolicy
+	cfg.LoadSubtitles = o.Subtitles
+	cfg.Captions = captions.Options{
+		Locales:   o.SubtitleLocales,
+		Format:    o.SubtitleFormat,
+		Auto:      o.SubtitleAuto,
+		Strip:     o.SubtitleStrip,
+		Placement: o.SubtitlePlacement,
+	}
+	cfg.AudioOnly = o.AudioOnly
+	cfg.PathProfile = o.PathProfile
+	cfg.Layout = o.Layout
+	cfg.CourseTemplate = o.CourseTemplate
+	cfg.ChapterTemplate = o.ChapterTemplate
+	cfg.LectureTemplate = o.LectureTemplate
+	cfg.Courses = nil
+}
+
+// ManifestEntry describes a backed up asset
+type ManifestEntry struct {
+	Path         string    `json:"path"` // relative to the course directory, with "/" separators
+	Type         AssetType `json:"type"`
+	CourseID     int       `json:"course_id"`
+	ChapterID    int       `json:"chapter_id,omitempty"`
+	LectureID    int       `json:"lecture_id,omitempty"`
+	AssetID      int       `json:"asset_id,omitempty"`
+	Variant      string    `json:"variant,omitempty"`
+	RemoteURL    string    `json:"remote_url,omitempty"`
+	HLS          bool      `json:"hls,omitempty"` // RemoteURL is a playlist, not the file itself
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// Key identifies the asset of the entry, as in Asset.Key
+func (e *ManifestEntry) Key() string {
+	return assetKey(e.Type, e.LectureID, e.AssetID, e.Variant, e.Path)
+}
+
+// LoadManifest reads the manifest of a course directory.
+// An empty manifest is returned when the file does not exist yet.
+func LoadManifest(courseDir string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(courseDir, ManifestFileName))
+	if os.IsNotExist(err) {
+		return &Manifest{Version: ManifestVersion}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err = json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// LoadManifest reads the manifest for the course
+func (b *Backuper) LoadManifest(course *client.Course) (*Manifest, error) {
+	m, err := LoadManifest(b.getCourseDirectory(course))
+	if err != nil {
+		return nil, err
+	}
+	m.CourseID = course.ID
+	m.CourseTitle = course.Title
+	return m, nil
+}
+
+// SaveManifest writes the manifest into the course directory
+func (b *Backuper) SaveManifest(course *client.Course, m *Manifest) error {
+	return m.Save(b.getCourseDirectory(course))
+}
+
+// Save writes the manifest into courseDir, through a temporary file
+func (m *Manifest) Save(courseDir string) error {
+	m.mu.Lock()
+	m.Version = ManifestVersion
+	m.UpdatedAt = time.Now().UTC()
+	sort.Slice(m.Entries, func(i, j int) bool {
+		return m.Entries[i].Path < m.Entries[j].Path
+	})
+	data, err := json.MarshalIndent(m, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	p := filepath.Join(courseDir, ManifestFileName)
+	if err = ioutil.WriteFile(p+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(p+".tmp", p)
+}
+
+// Lookup returns the entry for the given path (relative to the course directory), or nil
+func (m *Manifest) Lookup(path string) *ManifestEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.ToSlash(path)
+	for _, e := range m.Entries {
+		if e.Path == path {
+			return e
+		}
+	}
+	return nil
+}
+
+// Record hashes the local file of the asset, and adds (or replaces) its entry
+func (m *Manifest) Record(courseDir string, a Asset) (*ManifestEntry, error) {
+	rel, err := filepath.Rel(courseDir, a.LocalPath)
+	if err != nil {
+		return nil, err
+	}
+	sum, size, err := hashFile(a.LocalPath)
+	if err != nil {
+		return nil, err
+	}
+	e := &ManifestEntry{
+		Path:         filepath.ToSlash(rel),
+		Type:         a.Type,
+		CourseID:     a.CourseID,
+		ChapterID:    a.ChapterID,
+		LectureID:    a.LectureID,
+		AssetID:      a.AssetID,
+		Variant:      a.Variant,
+		RemoteURL:    a.RemoteURL,
+		HLS:          a.HLS,
+		Size:         size,
+		SHA256:       sum,
+		DownloadedAt: time.Now().UTC(),
+	}
+	m.add(e)
+	return e, nil
+}
+
+// Remove drops the entry for the given path (relative to the course directory)
+func (m *Manifest) Remove(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.ToSlash(path)
+	for i, e := range m.Entries {
+		if e.Path == path {
+			m.Entries = append(m.Entries[:i], m.Entries[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *Manifest) add(e *ManifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, old := range m.Entries {
+		if old.Path == e.Path {
+			m.Entries[i] = e
+			return
+		}
+	}
+	m.Entries = append(m.Entries, e)
+}
+
+// hashFile returns the hex-encoded SHA-256 and the size of a file
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
@@ -0,0 +1,145 @@
+package backup
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	spacesRegexp   = regexp.MustCompile(`\s+`)
+	newlinesRegexp = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToMarkdown converts HTML nodes (and their descendants) into Markdown
+func htmlToMarkdown(nodes ...*html.Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		b.WriteString(markdownChildren(n, false))
+	}
+	md := newlinesRegexp.ReplaceAllString(b.String(), "\n\n")
+	return strings.TrimSpace(md) + "\n"
+}
+
+func markdownChildren(n *html.Node, ordered bool) string {
+	var b strings.Builder
+	index := 1
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "li" {
+			b.WriteString(markdownListItem(c, ordered, index))
+			index++
+			continue
+		}
+		b.WriteString(markdownNode(c))
+	}
+	return b.String()
+}
+
+func markdownNode(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return spacesRegexp.ReplaceAllString(n.Data, " ")
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ := strconv.Atoi(n.Data[1:])
+		return "\n\n" + strings.Repeat("#", level) + " " + strings.TrimSpace(markdownChildren(n, false)) + "\n\n"
+	case "p", "div", "section", "article", "header", "footer", "figure", "table":
+		return "\n\n" + strings.TrimSpace(markdownChildren(n, false)) + "\n\n"
+	case "tr", "figcaption":
+		return strings.TrimSpace(markdownChildren(n, false)) + "\n"
+	case "td", "th":
+		return strings.TrimSpace(markdownChildren(n, false)) + " "
+	case "br":
+		return "  \n"
+	case "hr":
+		return "\n\n---\n\n"
+	case "strong", "b":
+		return wrapInline("**", markdownChildren(n, false))
+	case "em", "i":
+		return wrapInline("_", markdownChildren(n, false))
+	case "code":
+		return wrapInline("`", textContent(n))
+	case "pre":
+		return "\n\n```\n" + strings.Trim(textContent(n), "\n") + "\n```\n\n"
+	case "a":
+		text := strings.TrimSpace(markdownChildren(n, false))
+		href := attr(n, "href")
+		if href == "" {
+			return text
+		}
+		if text == "" {
+			text = href
+		}
+		return "[" + text + "](" + href + ")"
+	case "img":
+		return "![" + attr(n, "alt") + "](" + attr(n, "src") + ")"
+	case "ul", "ol":
+		return "\n\n" + markdownChildren(n, n.Data == "ol") + "\n\n"
+	case "blockquote":
+		lines := strings.Split(strings.TrimSpace(markdownChildren(n, false)), "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight("> "+l, " ")
+		}
+		return "\n\n" + strings.Join(lines, "\n") + "\n\n"
+	}
+	return markdownChildren(n, false)
+}
+
+func markdownListItem(n *html.Node, ordered bool, index int) string {
+	marker := "- "
+	if ordered {
+		marker = strconv.Itoa(index) + ". "
+	}
+	contents := newlinesRegexp.ReplaceAllString(strings.TrimSpace(markdownChildren(n, false)), "\n\n")
+	lines := strings.Split(contents, "\n")
+	for i, l := range lines {
+		if i == 0 {
+			lines[i] = marker + l
+		} else if l != "" {
+			lines[i] = strings.Repeat(" ", len(marker)) + l // <- nested blocks are indented
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// wrapInline surrounds s with the given delimiter, keeping the outer spaces outside
+func wrapInline(delim, s string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	lead := s[:strings.Index(s, trimmed)]
+	trail := s[len(lead)+len(trimmed):]
+	return lead + delim + trimmed + delim + trail
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "br" {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
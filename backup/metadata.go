@@ -0,0 +1,260 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+// courseExport is the JSON description of a course, written at the course root
+type courseExport struct {
+	ID           int                `json:"id"`
+	Title        string             `json:"title"`
+	URL          string             `json:"url"`
+	Headline     string             `json:"headline,omitempty"`
+	Description  string             `json:"description,omitempty"` // HTML
+	Instructors  []instructorExport `json:"instructors,omitempty"`
+	Objectives   []string           `json:"objectives,omitempty"`
+	Requirements []string           `json:"requirements,omitempty"`
+	Language     string             `json:"language,omitempty"`
+	Locale       string             `json:"locale,omitempty"`
+	LastUpdated  string             `json:"last_updated,omitempty"`
+	Image        string             `json:"image,omitempty"` // relative to the course directory
+	Curriculum   []chapterExport    `json:"curriculum"`
+}
+
+type instructorExport struct {
+	Name     string `json:"name"`
+	JobTitle string `json:"job_title,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+type chapterExport struct {
+	Index int          `json:"index,omitempty"` // 0 for the items before the first chapter
+	Title string       `json:"title,omitempty"`
+	Items []itemExport `json:"items"`
+}
+
+type itemExport struct {
+	Type  string `json:"type"` // "lecture", "quiz", "practice-test", "coding-exercise" or "practice"
+	Index int    `json:"index"`
+	Title string `json:"title"`
+}
+
+// ListCourseMetadataAssets returns the description of the course (from its details) as a
+// "course.json" file, a "README.md" file and the cover image, at the course root (along
+// with the NFO files of the show layout)
+func (b *Backuper) ListCourseMetadataAssets(ctx context.Context, course *client.Course, curriculum []interface{}) ([]Asset, error) {
+	if b.details == nil || b.details.ID != course.ID {
+		if err := b.loadDetails(ctx, course); err != nil {
+			return nil, err
+		}
+	}
+	details := b.courseDetails(course)
+	export := newCourseExport(course, details, curriculum)
+
+	courseDir := b.getCourseDirectory(course)
+	var assets []Asset
+	if imageURL := courseImageURL(details); imageURL != "" {
+		export.Image = "cover" + imageExt(imageURL)
+		if b.Layout == ShowLayout {
+			export.Image = "poster" + imageExt(imageURL) // <- as expected by the media servers
+		}
+		assets = append(assets, Asset{
+			LocalPath: filepath.Join(courseDir, export.Image),
+			RemoteURL: imageURL,
+			Type:      AssetCourse,
+		})
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	assets = append(assets,
+		Asset{
+			LocalPath: filepath.Join(courseDir, "course.json"),
+			Contents:  data,
+			Type:      AssetCourse,
+			Refresh:   true, // <- the course gets updated
+		},
+		Asset{
+			LocalPath: filepath.Join(courseDir, "README.md"),
+			Contents:  courseToMarkdown(export),
+			Type:      AssetCourse,
+			Refresh:   true,
+		},
+	)
+	if b.Layout == ShowLayout {
+		showAssets, err := b.listShowAssets(course, details, curriculum)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, showAssets...)
+	}
+	for i := range assets {
+		assets[i].CourseID = course.ID
+	}
+	return assets, nil
+}
+
+// loadDetails loads the details of the course (description, instructors, locale...).
+// When they cannot be loaded, a warning is reported and the course is used as is.
+func (b *Backuper) loadDetails(ctx context.Context, course *client.Course) error {
+	details, err := b.Client.GetCourse(ctx, course.ID)
+	if err != nil && ctx.Err() != nil {
+		return err
+	} else if err != nil {
+		b.warn(fmt.Errorf("%s: could not load the details: %v", course.Title, err))
+		details = course // <- limited to the listing
+	}
+	b.details = details
+	return nil
+}
+
+// courseDetails returns the details of the course, or the course itself when they were
+// not loaded
+func (b *Backuper) courseDetails(course *client.Course) *client.Course {
+	if b.details != nil && b.details.ID == course.ID {
+		return b.details
+	}
+	return course
+}
+
+func newCourseExport(course, details *client.Course, curriculum []interface{}) *courseExport {
+	if details == nil {
+		details = course
+	}
+	export := &courseExport{
+		ID:           course.ID,
+		Title:        course.Title,
+		URL:          client.WebURL + course.URL,
+		Headline:     details.Headline,
+		Description:  details.Description,
+		Objectives:   details.Objectives.Items,
+		Requirements: details.Requirements.Items,
+		LastUpdated:  details.LastUpdateDate,
+	}
+	if details.Locale != nil {
+		export.Language = details.Locale.Title
+		export.Locale = details.Locale.Locale
+	}
+	for _, u := range details.Instructors {
+		i := instructorExport{Name: u.DisplayName, JobTitle: u.JobTitle}
+		if i.Name == "" {
+			i.Name = u.Title
+		}
+		if u.URL != "" {
+			i.URL = client.WebURL + u.URL
+		}
+		export.Instructors = append(export.Instructors, i)
+	}
+
+	// the curriculum, grouped by chapter
+	var current *chapterExport
+	add := func(item itemExport) {
+		if current == nil {
+			export.Curriculum = append(export.Curriculum, chapterExport{})
+			current = &export.Curriculum[len(export.Curriculum)-1]
+		}
+		current.Items = append(current.Items, item)
+	}
+	for _, item := range curriculum {
+		switch i := item.(type) {
+		case *client.Chapter:
+			export.Curriculum = append(export.Curriculum, chapterExport{Index: i.ObjectIndex, Title: i.Title})
+			current = &export.Curriculum[len(export.Curriculum)-1]
+		case *client.Lecture:
+			add(itemExport{Type: "lecture", Index: i.ObjectIndex, Title: i.Title})
+		case *client.Quiz:
+			t := i.Type
+			if t == "" || t == "simple-quiz" {
+				t = "quiz"
+			}
+			add(itemExport{Type: t, Index: i.ObjectIndex, Title: i.Title})
+		case *client.Practice:
+			add(itemExport{Type: "practice", Index: i.ObjectIndex, Title: i.Title})
+		}
+	}
+	return export
+}
+
+// courseToMarkdown writes the description of the course
+func courseToMarkdown(c *courseExport) []byte {
+	w := new(bytes.Buffer)
+	fmt.Fprintf(w, "# %s\n\n", c.Title)
+	if c.Headline != "" {
+		fmt.Fprintf(w, "_%s_\n\n", c.Headline)
+	}
+	if c.Image != "" {
+		fmt.Fprintf(w, "![%s](%s)\n\n", c.Title, c.Image)
+	}
+	if len(c.Instructors) > 0 {
+		names := make([]string, len(c.Instructors))
+		for i, in := range c.Instructors {
+			names[i] = in.Name
+			if in.JobTitle != "" {
+				names[i] += " (" + in.JobTitle + ")"
+			}
+		}
+		fmt.Fprintf(w, "- **Instructors:** %s\n", strings.Join(names, ", "))
+	}
+	if c.Language != "" {
+		fmt.Fprintf(w, "- **Language:** %s\n", c.Language)
+	}
+	if c.LastUpdated != "" {
+		fmt.Fprintf(w, "- **Last updated:** %s\n", c.LastUpdated)
+	}
+	fmt.Fprintf(w, "- **Udemy:** %s\n\n", c.URL)
+
+	if len(c.Objectives) > 0 {
+		fmt.Fprintf(w, "## What you'll learn\n\n")
+		for _, o := range c.Objectives {
+			fmt.Fprintf(w, "- %s\n", inlineMarkdown(o))
+		}
+		fmt.Fprintln(w)
+	}
+	if len(c.Requirements) > 0 {
+		fmt.Fprintf(w, "## Requirements\n\n")
+		for _, r := range c.Requirements {
+			fmt.Fprintf(w, "- %s\n", inlineMarkdown(r))
+		}
+		fmt.Fprintln(w)
+	}
+	if c.Description != "" {
+		fmt.Fprintf(w, "## Description\n\n%s\n\n", strings.TrimSpace(fragmentToMarkdown(c.Description)))
+	}
+
+	fmt.Fprintf(w, "## Curriculum\n\n")
+	for _, chap := range c.Curriculum {
+		if chap.Title != "" {
+			fmt.Fprintf(w, "### %d. %s\n\n", chap.Index, chap.Title)
+		}
+		for _, item := range chap.Items {
+			if item.Type == "lecture" {
+				fmt.Fprintf(w, "- %d\\. %s\n", item.Index, item.Title)
+			} else {
+				fmt.Fprintf(w, "- %d\\. %s (%s)\n", item.Index, item.Title, item.Type)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return append(bytes.TrimRight(w.Bytes(), "\n"), '\n')
+}
+
+// courseImageURL returns the URL of the largest image of the course
+func courseImageURL(c *client.Course) string {
+	if c == nil {
+		return ""
+	}
+	for _, u := range []string{c.Image750x422, c.Image480x270, c.Image240x135} {
+		if u != "" {
+			return u
+		}
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+// Naming holds the text/template templates of the file names, as:
+//
+//	{{printf "%02d" .Chapter.ObjectIndex}} - {{.Chapter.Title}}
+//
+// Each template is optional (nil gives the default names). The names are sanitized like
+// the default ones: a template cannot create sub-directories.
+type Naming struct {
+	Course  *template.Template // course directory
+	Chapter *template.Template // chapter directories
+	Lecture *template.Template // prefix of the lecture files (also used for quizzes and practices)
+}
+
+// NameData is the data given to the naming templates
+type NameData struct {
+	Course     *client.Course  // with its details (as its headline and instructors)
+	Chapter    *client.Chapter // nil for the course template, and the courses without chapters
+	Lecture    *client.Lecture // nil for the course and chapter templates
+	Asset      *client.Asset   // main asset of the lecture (nil for quizzes and practices)
+	Resolution int             // height of the downloaded video (the preferred one for HLS streams)
+	Locale     string          // locale of the course (as "en_US")
+	Season     int             // number of the chapter (0 for the items before the first chapter)
+	Episode    int             // number of the lecture among the media lectures of its chapter (the other items get the previous number)
+}
+
+// ParseNaming parses the naming templates (empty ones are left nil)
+func ParseNaming(course, chapter, lecture string) (*Naming, error) {
+	n := &Naming{}
+	for _, t := range []struct {
+		name string
+		text string
+		dst  **template.Template
+	}{
+		{"course", course, &n.Course},
+		{"chapter", chapter, &n.Chapter},
+		{"lecture", lecture, &n.Lecture},
+	} {
+		if strings.TrimSpace(t.text) == "" {
+			continue
+		}
+		tmpl, err := template.New(t.name).Parse(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("naming: %v", err)
+		}
+		*t.dst = tmpl
+	}
+	return n, nil
+}
+
+// nameData returns the data of the templates, for the course
+func (b *Backuper) nameData(course *client.Course) *NameData {
+	course = b.courseDetails(course) // <- the listings only give the title
+	data := &NameData{Course: course, Resolution: b.Resolution}
+	if course.Locale != nil {
+		data.Locale = course.Locale.Locale
+	}
+	return data
+}
+
+// formatName runs a naming template: ok is false when the default name should be used
+// (no template, or the template failed)
+func (b *Backuper) formatName(t *template.Template, data *NameData) (name string, ok bool) {
+	if t == nil {
+		return "", false
+	}
+	var buf bytes.Buffer
+	err := t.Execute(&buf, data)
+	if err == nil && strings.TrimSpace(buf.String()) == "" {
+		err = errors.New("empty name")
+	}
+	if err != nil {
+		if !b.namingFailed[t.Name()] { // <- warn once per template, not for each file
+			if b.namingFailed == nil {
+				b.namingFailed = make(map[string]bool)
+			}
+			b.namingFailed[t.Name()] = true
+			b.warn(fmt.Errorf("naming: %s template: %v, using the default name", t.Name(), err))
+		}
+		return "", false
+	}
+	return strings.TrimSpace(buf.String()), true
+}
+
+// naming returns the templates of the file names, completed by the ones of the layout
+func (b *Backuper) naming() *Naming {
+	n := &Naming{}
+	if b.Naming != nil {
+		*n = *b.Naming
+	}
+	if b.Layout == ShowLayout {
+		if n.Course == nil {
+			n.Course = showNaming.Course
+		}
+		if n.Chapter == nil {
+			n.Chapter = showNaming.Chapter
+		}
+		if n.Lecture == nil {
+			n.Lecture = showNaming.Lecture
+		}
+	}
+	return n
+}
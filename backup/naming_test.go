@@ -0,0 +1,36 @@
+package backup
+
+import (
+	"testing"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+func TestFormatName(t *testing.T) {
+	n, err := ParseNaming("", "", "{{.Lecture.ObjectIndex}} {{.Lecture.Title}}{{if not .Lecture.Title}}{{.Asset.Title}}{{end}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warnings int
+	b := &Backuper{Naming: n, Warn: func(error) { warnings++ }}
+
+	data := &NameData{Lecture: &client.Lecture{ObjectIndex: 3, Title: "Intro"}}
+	if name, ok := b.formatName(n.Lecture, data); !ok || name != "3 Intro" {
+		t.Errorf("formatName = %q, %v, want %q", name, ok, "3 Intro")
+	}
+
+	// the failures give the default names, with a single warning
+	for i := 0; i < 3; i++ {
+		data := &NameData{Lecture: &client.Lecture{ObjectIndex: i}} // <- nil .Asset
+		if name, ok := b.formatName(n.Lecture, data); ok {
+			t.Errorf("formatName = %q, want a failure", name)
+		}
+	}
+	if warnings != 1 {
+		t.Errorf("%d warnings, want 1", warnings)
+	}
+
+	if _, ok := b.formatName(nil, data); ok {
+		t.Error("formatName(nil) should give the default name")
+	}
+}
@@ -1,29 +1,220 @@
 package backup
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
+	"net/url"
+	"path"
 	"path/filepath"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/ushu/udemy-backup/backup/resolution"
 	"github.com/ushu/udemy-backup/client"
+	"golang.org/x/text/unicode/norm"
 )
 
-var pathSanitizer = strings.NewReplacer("/", "|", ":", " - ")
+// PathProfile tells on which file systems the backed up files must be usable
+type PathProfile string
 
-func getCourseDirectory(rootDir string, course *client.Course) string {
-	slug := getCourseSlug(course)
-	return filepath.Join(rootDir, slug)
+const (
+	// POSIX only forbids "/" (":" is replaced as well, since the macOS Finder shows it
+	// as "/")
+	POSIX PathProfile = "posix"
+	// Windows forbids the <>:"/\|?* characters, the trailing dots and spaces, and the
+	// reserved device names (as CON or LPT1); the paths are kept short for MAX_PATH
+	Windows PathProfile = "windows"
+	// ExFAT has the character rules of Windows, for the external drives
+	ExFAT PathProfile = "exfat"
+	// ASCII has the rules of Windows, with the names transliterated to ASCII
+	ASCII PathProfile = "ascii"
+)
+
+// Validate checks the profile (empty means POSIX)
+func (p PathProfile) Validate() error {
+	switch p {
+	case "", POSIX, Windows, ExFAT, ASCII:
+		return nil
+	}
+	return fmt.Errorf("backup: invalid path profile %q", p)
+}
+
+const (
+	// minimum size of a truncated name, in bytes
+	minNameSize = 32
+	// room kept for the suffixes added to the lecture prefixes (as ".en_US.auto.srt")
+	prefixReserve = 24
+)
+
+// Sanitizer makes the file names valid for a profile. The names are normalized to the
+// Unicode NFC form, and the names that are too long are truncated, with a hash suffix to
+// keep them distinct.
+type Sanitizer struct {
+	Profile PathProfile
+	MaxName int // maximum size of a path element, in bytes
+	MaxPath int // maximum size of a path relative to the backup directory, in bytes
+}
+
+// NewSanitizer returns a sanitizer with the limits of the profile
+func NewSanitizer(p PathProfile) *Sanitizer {
+	s := &Sanitizer{Profile: p, MaxName: 255, MaxPath: 1024}
+	if p == Windows || p == ASCII {
+		s.MaxPath = 200 // <- leaves room for the directory the backup is copied into
+	}
+	return s
+}
+
+var (
+	posixReplacer   = strings.NewReplacer("/", "|", ":", " - ")
+	windowsReplacer = strings.NewReplacer(
+		"/", "-", "\\", "-", "|", "-", ":", " - ",
+		"*", "", "?", "", "\"", "'", "<", "(", ">", ")",
+	)
+	asciiReplacer = strings.NewReplacer(
+		"‘", "'", "’", "'", "“", "\"", "”", "\"",
+		"–", "-", "—", "-", "…", "...", "«", "\"", "»", "\"",
+	)
+)
+
+// device names reserved by Windows, with or without extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Name returns the sanitized path element (directory or file name)
+func (s *Sanitizer) Name(name string) string {
+	return truncateName(s.clean(name), s.MaxName)
+}
+
+// NameIn returns the sanitized name of a file or directory in dir, truncated so that its
+// path (relative to root) fits MaxPath, keeping reserve bytes for the suffixes added later
+func (s *Sanitizer) NameIn(root, dir, name string, reserve int) string {
+	return truncateName(s.clean(name), s.maxNameIn(root, dir, reserve))
+}
+
+// maxNameIn returns the maximum size of a name in dir, as used by NameIn
+func (s *Sanitizer) maxNameIn(root, dir string, reserve int) int {
+	max := s.MaxName
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		rel = dir
+	}
+	if n := s.MaxPath - len(rel) - 1; rel != "." && n < max {
+		max = n
+	}
+	max -= reserve
+	if max < minNameSize {
+		max = minNameSize
+	}
+	return max
 }
 
-func getChapterDirectory(rootDir string, course *client.Course, chapter *client.Chapter) string {
-	base := getCourseDirectory(rootDir, course)
+func (s *Sanitizer) clean(name string) string {
+	name = norm.NFC.String(name)
+	if s.Profile == ASCII {
+		name = toASCII(name)
+	}
+	strict := s.Profile == Windows || s.Profile == ExFAT || s.Profile == ASCII
+	if strict {
+		name = windowsReplacer.Replace(name)
+	} else {
+		name = posixReplacer.Replace(name)
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if strict {
+		name = strings.TrimRight(name, ". ")
+		base := strings.ToUpper(strings.TrimSpace(strings.SplitN(name, ".", 2)[0]))
+		if windowsReservedNames[base] {
+			name = name[:len(base)] + "_" + name[len(base):]
+		}
+	}
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// toASCII removes the accents, and replaces the other non-ASCII characters
+func toASCII(name string) string {
+	name = norm.NFD.String(asciiReplacer.Replace(name))
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			return -1 // <- combining accent
+		case r >= utf8.RuneSelf:
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// truncateName shortens name to max bytes (keeping its extension), with a suffix derived
+// from the full name, so that the truncated names are stable and distinct
+func truncateName(name string, max int) string {
+	if len(name) <= max {
+		return name
+	}
+	sum := sha1.Sum([]byte(name))
+	suffix := "~" + hex.EncodeToString(sum[:4])
+	ext := ""
+	if hasExt(name) {
+		ext = path.Ext(name)
+	}
+	n := max - len(suffix) - len(ext)
+	if n < 0 {
+		n = 0
+	}
+	stem := name[:len(name)-len(ext)]
+	for n > 0 && !utf8.RuneStart(stem[n]) {
+		n-- // <- do not cut a character
+	}
+	return strings.TrimRight(stem[:n], ". ") + suffix + ext
+}
+
+// paths returns the sanitizer of the file names
+func (b *Backuper) paths() *Sanitizer {
+	if b.Paths == nil {
+		return NewSanitizer(POSIX)
+	}
+	return b.Paths
+}
+
+func (b *Backuper) getCourseDirectory(course *client.Course) string {
+	if b.CourseDir != "" {
+		return b.CourseDir
+	}
+	name := getCourseSlug(course)
+	if s, ok := b.formatName(b.naming().Course, b.nameData(course)); ok {
+		name = b.paths().NameIn(b.RootDir, b.RootDir, s, 2*minNameSize+prefixReserve)
+	}
+	return filepath.Join(b.RootDir, name)
+}
+
+func (b *Backuper) getChapterDirectory(course *client.Course, chapter *client.Chapter) string {
+	base := b.getCourseDirectory(course)
 	if chapter == nil {
 		return base // some courses have no chapters
 	}
 
-	title := pathSanitizer.Replace(chapter.Title)
-	chapterDirName := fmt.Sprintf("%d. %s", chapter.ObjectIndex, title)
-	return filepath.Join(base, chapterDirName)
+	data := b.nameData(course)
+	data.Chapter, data.Season = chapter, seasonNumber(chapter)
+	chapterDirName, ok := b.formatName(b.naming().Chapter, data)
+	if !ok {
+		chapterDirName = fmt.Sprintf("%d. %s", chapter.ObjectIndex, chapter.Title)
+	}
+	return filepath.Join(base, b.paths().NameIn(b.RootDir, base, chapterDirName, minNameSize+prefixReserve))
 }
 
 func getCourseSlug(course *client.Course) string {
@@ -34,13 +225,76 @@ func getCourseSlug(course *client.Course) string {
 	return el[1]
 }
 
-func getLecturePrefix(lecture *client.Lecture) string {
-	prefix := fmt.Sprintf("%d. %s", lecture.ObjectIndex, lecture.Title)
-	return pathSanitizer.Replace(prefix)
+// getLecturePrefix returns the prefix of the lecture files, given the video to download
+// (if any)
+func (b *Backuper) getLecturePrefix(course *client.Course, chapDir string, lecture *client.Lecture, video *client.Video) string {
+	data := b.nameData(course)
+	data.Chapter, data.Lecture, data.Asset = lecture.Chapter, lecture, lecture.Asset
+	if video != nil {
+		if h := resolution.ParseLabel(video.Label); h > 0 {
+			data.Resolution = h
+		}
+	}
+	return b.getItemPrefix(chapDir, "lecture", data)
+}
+
+func (b *Backuper) getQuizPrefix(course *client.Course, chapDir string, quiz *client.Quiz) string {
+	data := b.nameData(course)
+	data.Chapter = quiz.Chapter
+	data.Lecture = &client.Lecture{Chapter: quiz.Chapter, ID: quiz.ID, Title: quiz.Title, ObjectIndex: quiz.ObjectIndex}
+	return b.getItemPrefix(chapDir, "quiz", data)
+}
+
+func (b *Backuper) getPracticePrefix(course *client.Course, chapDir string, practice *client.Practice) string {
+	data := b.nameData(course)
+	data.Chapter = practice.Chapter
+	data.Lecture = &client.Lecture{Chapter: practice.Chapter, ID: practice.ID, Title: practice.Title, ObjectIndex: practice.ObjectIndex}
+	return b.getItemPrefix(chapDir, "practice", data)
+}
+
+// getItemPrefix returns the prefix of the files of a curriculum item (given as data.Lecture)
+func (b *Backuper) getItemPrefix(chapDir, kind string, data *NameData) string {
+	data.Season = seasonNumber(data.Chapter)
+	data.Episode = b.episode(kind, data.Lecture.ID, data.Lecture.ObjectIndex)
+	prefix, ok := b.formatName(b.naming().Lecture, data)
+	if !ok {
+		prefix = fmt.Sprintf("%d. %s", data.Lecture.ObjectIndex, data.Lecture.Title)
+	}
+	return b.uniquePrefix(chapDir, fmt.Sprintf("%s/%d", kind, data.Lecture.ID), b.paths().NameIn(b.RootDir, chapDir, prefix, prefixReserve))
 }
 
-func getLectureAssetsDirectory(rootDir string, course *client.Course, lecture *client.Lecture) string {
-	dir := getChapterDirectory(rootDir, course, lecture.Chapter)
-	prefix := getLecturePrefix(lecture)
-	return filepath.Join(dir, prefix)
+// uniquePrefix gives a distinct prefix to each curriculum item of a directory: the items
+// sharing a name (as with a lecture template without the index, or with the same title)
+// get a " (2)", " (3)"... suffix, in curriculum order, so that all their files (articles,
+// quizzes, captions...) are renamed together. The prefixes are compared case-insensitively.
+func (b *Backuper) uniquePrefix(dir, item, prefix string) string {
+	if b.prefixes == nil {
+		b.prefixes, b.prefixed = make(map[string]string), make(map[string]bool)
+	}
+	if p, ok := b.prefixes[item]; ok {
+		return p // <- the item was already named
+	}
+	max := b.paths().maxNameIn(b.RootDir, dir, prefixReserve)
+	name := prefix
+	for n := 2; ; n++ {
+		k := strings.ToLower(filepath.Join(dir, name))
+		if !b.prefixed[k] {
+			b.prefixed[k] = true
+			b.prefixes[item] = name
+			return name
+		}
+		s := fmt.Sprintf(" (%d)", n)
+		name = truncateName(prefix, max-len(s)) + s
+	}
+}
+
+// imageExt returns the extension of an image, from its URL
+func imageExt(rawurl string) string {
+	if u, err := url.Parse(rawurl); err == nil {
+		switch ext := strings.ToLower(path.Ext(u.Path)); ext {
+		case ".png", ".jpg", ".jpeg", ".gif", ".webp":
+			return ext
+		}
+	}
+	return ".jpg"
 }
@@ -0,0 +1,219 @@
+package backup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+func TestSanitizerName(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	tests := []struct {
+		profile PathProfile
+		name    string
+		want    string
+	}{
+		// POSIX keeps the names of the previous versions
+		{POSIX, "1. Intro: the basics", "1. Intro -  the basics"},
+		{POSIX, "TCP/IP", "TCP|IP"},
+		{POSIX, "What? *Really*", "What? *Really*"},
+		{POSIX, "CON", "CON"},
+		{POSIX, "  padded  ", "padded"},
+		{POSIX, "tab\there", "tabhere"},
+		{POSIX, "..", "_"},
+		{POSIX, "", "_"},
+
+		// Unicode is normalized to NFC
+		{POSIX, "Cafe\u0301", "Caf\u00e9"},
+
+		// Windows and exFAT
+		{Windows, "What? *Really*", "What Really"},
+		{Windows, `Say "hi" <now>`, "Say 'hi' (now)"},
+		{Windows, `C:\path|pipe`, "C - -path-pipe"},
+		{Windows, "The end...", "The end"},
+		{Windows, "trailing . ", "trailing"},
+		{Windows, "CON", "CON_"},
+		{Windows, "con.txt", "con_.txt"},
+		{Windows, "LPT1 .md", "LPT1_ .md"},
+		{Windows, "CONSOLE", "CONSOLE"},
+		{ExFAT, "a?b", "ab"},
+		{ExFAT, "AUX", "AUX_"},
+
+		// ASCII only
+		{ASCII, "Café déjà vu", "Cafe deja vu"},
+		{ASCII, "It’s “quoted” — ok…", `It's 'quoted' - ok`},
+		{ASCII, "日本語", "___"},
+
+		// long names are truncated, with a stable suffix
+		{POSIX, long, strings.Repeat("a", 246) + "~003ef1ba"},
+		{POSIX, long + ".mp4", strings.Repeat("a", 242) + "~685a2fe4.mp4"},
+	}
+	for _, tt := range tests {
+		s := NewSanitizer(tt.profile)
+		if got := s.Name(tt.name); got != tt.want {
+			t.Errorf("%s: Name(%q) = %q, want %q", tt.profile, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizerTruncation(t *testing.T) {
+	tests := []struct {
+		name string
+		max  int
+	}{
+		{strings.Repeat("é", 200), 255},         // <- characters are not cut
+		{strings.Repeat("x", 100) + ".pdf", 40}, // <- the extension is kept
+		{strings.Repeat("x", 39), 40},
+	}
+	for _, tt := range tests {
+		got := truncateName(tt.name, tt.max)
+		if len(got) > tt.max {
+			t.Errorf("truncateName(%q, %d) = %q: too long", tt.name, tt.max, got)
+		}
+		if !strings.HasSuffix(got, filepath.Ext(tt.name)) {
+			t.Errorf("truncateName(%q, %d) = %q: lost extension", tt.name, tt.max, got)
+		}
+		if got != truncateName(tt.name, tt.max) {
+			t.Errorf("truncateName(%q, %d) is not stable", tt.name, tt.max)
+		}
+		for _, r := range got {
+			if r == '\ufffd' {
+				t.Errorf("truncateName(%q, %d) = %q: cut a character", tt.name, tt.max, got)
+			}
+		}
+	}
+
+	// distinct names stay distinct
+	a := truncateName(strings.Repeat("x", 300)+"a", 64)
+	b := truncateName(strings.Repeat("x", 300)+"b", 64)
+	if a == b {
+		t.Errorf("truncated names collide: %q", a)
+	}
+}
+
+func TestSanitizerNameIn(t *testing.T) {
+	root := filepath.Join("backups")
+	dir := filepath.Join(root, "course", strings.Repeat("c", 100))
+	tests := []struct {
+		profile PathProfile
+		reserve int
+		maxPath int
+	}{
+		{Windows, 0, 200},
+		{Windows, prefixReserve, 200 - prefixReserve},
+		{POSIX, 0, 1024},
+	}
+	for _, tt := range tests {
+		s := NewSanitizer(tt.profile)
+		name := s.NameIn(root, dir, strings.Repeat("n", 300), tt.reserve)
+		rel, _ := filepath.Rel(root, filepath.Join(dir, name))
+		if len(rel) > tt.maxPath || len(name) > s.MaxName {
+			t.Errorf("%s: NameIn(reserve=%d) gives %d bytes (%d for the name)", tt.profile, tt.reserve, len(rel), len(name))
+		}
+	}
+
+	// deep directories still leave room for meaningful names
+	deep := filepath.Join(root, strings.Repeat("d", 250))
+	if name := NewSanitizer(Windows).NameIn(root, deep, strings.Repeat("n", 300), 0); len(name) != minNameSize {
+		t.Errorf("NameIn in a deep directory gives %d bytes, want %d", len(name), minNameSize)
+	}
+}
+
+func TestAllocatePathsLimits(t *testing.T) {
+	b := &Backuper{RootDir: "backups", Paths: NewSanitizer(Windows)}
+	dir := filepath.Join("backups", "course", "1. Intro")
+	name := b.paths().NameIn(b.RootDir, dir, strings.Repeat("n", 300)+".pdf", 0)
+	assets := []Asset{
+		{LocalPath: filepath.Join(dir, name), Type: AssetFile, AssetID: 1},
+		{LocalPath: filepath.Join(dir, name), Type: AssetFile, AssetID: 2},
+	}
+	b.allocatePaths(assets)
+	if assets[0].LocalPath == assets[1].LocalPath {
+		t.Fatalf("paths collide: %q", assets[0].LocalPath)
+	}
+	for _, a := range assets {
+		rel, _ := filepath.Rel(b.RootDir, a.LocalPath)
+		if len(rel) > b.Paths.MaxPath {
+			t.Errorf("%q: %d bytes, want at most %d", rel, len(rel), b.Paths.MaxPath)
+		}
+	}
+	if !strings.HasSuffix(assets[1].LocalPath, " (2).pdf") {
+		t.Errorf("%q: missing suffix", assets[1].LocalPath)
+	}
+}
+
+func TestRemoteFileName(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" {
+			t.Errorf("unexpected %s request", r.Method)
+		}
+		switch r.URL.Path {
+		case "/header":
+			w.Header().Set("Content-Disposition", `attachment; filename="Slides.pdf"`)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	b := &Backuper{Client: client.New(client.WithHTTPClient(srv.Client()))}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{srv.URL + "/header", "Slides.pdf"},
+		{srv.URL + "/header?response-content-disposition=attachment%3B%20filename%3Dsigned.zip", "signed.zip"},
+		{srv.URL + "/missing/code.zip", "code.zip"},
+		{srv.URL + "/missing/no-extension", ""},
+	}
+	for _, tt := range tests {
+		if got := b.remoteFileName(context.Background(), tt.url); got != tt.want {
+			t.Errorf("remoteFileName(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestUniquePrefix(t *testing.T) {
+	b := &Backuper{RootDir: "backups"}
+	dir := filepath.Join("backups", "course")
+	tests := []struct {
+		item   string
+		prefix string
+		want   string
+	}{
+		{"lecture/1", "Intro", "Intro"},
+		{"lecture/2", "Intro", "Intro (2)"},
+		{"quiz/3", "intro", "intro (3)"}, // <- case-insensitive
+		{"lecture/1", "Intro", "Intro"},  // <- the same item keeps its prefix
+		{"lecture/4", "Outro", "Outro"},
+	}
+	for _, tt := range tests {
+		if got := b.uniquePrefix(dir, tt.item, tt.prefix); got != tt.want {
+			t.Errorf("uniquePrefix(%q, %q) = %q, want %q", tt.item, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestAllocatePathsOrder(t *testing.T) {
+	b := &Backuper{RootDir: "backups"}
+	dir := filepath.Join("backups", "course")
+	readme := filepath.Join(dir, "README.md")
+	assets := []Asset{
+		{LocalPath: readme, Type: AssetFile, LectureID: 2, AssetID: 5},
+		{LocalPath: readme, Type: AssetArticle, LectureID: 2},
+		{LocalPath: readme, Type: AssetCourse},
+		{LocalPath: readme, Type: AssetArticle, LectureID: 1},
+	}
+	b.allocatePaths(assets)
+	want := []string{"README (4).md", "README (3).md", "README.md", "README (2).md"}
+	for i, a := range assets {
+		if got := filepath.Base(a.LocalPath); got != want[i] {
+			t.Errorf("asset %d: %q, want %q", i, got, want[i])
+		}
+	}
+}
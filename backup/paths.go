@@ -0,0 +1,185 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+// listFileAssets returns the downloadable files of an Udemy asset, in dir
+func (b *Backuper) listFileAssets(ctx context.Context, dir string, asset *client.Asset, files []*client.File) []Asset {
+	var assets []Asset
+	for i, f := range files {
+		if f == nil || f.File == "" {
+			continue
+		}
+		name := fileName(asset.Title, b.remoteFileName(ctx, f.File), f, len(files) > 1)
+		assets = append(assets, Asset{
+			LocalPath: filepath.Join(dir, b.paths().NameIn(b.RootDir, dir, name, 0)),
+			RemoteURL: f.File,
+			Type:      AssetFile,
+			AssetID:   asset.ID,
+			Variant:   strconv.Itoa(i),
+		})
+	}
+	return assets
+}
+
+// fileName returns the local name of a downloaded file. The title of the asset is kept
+// when it looks like a file name (as it was by the previous versions); otherwise the name
+// is the remote one (see remoteFileName). When the asset has several files (shared is
+// set), the title only serves as a fallback, along with the label of the file.
+func fileName(title, remote string, f *client.File, shared bool) string {
+	title = strings.TrimSpace(title)
+	switch {
+	case !shared && hasExt(title):
+		return title
+	case shared && remote != "":
+		return remote
+	case remote != "" && title != "":
+		return title + path.Ext(remote) // <- keep the title, with the type of the file
+	case remote != "":
+		return remote
+	}
+	if title == "" {
+		title = "file"
+	}
+	base, ext := title, ""
+	if hasExt(title) {
+		ext = path.Ext(title)
+		base = strings.TrimSuffix(title, ext)
+	}
+	if label := strings.TrimSpace(f.Label); shared && label != "" {
+		base = fmt.Sprintf("%s (%s)", base, label)
+	}
+	return base + ext
+}
+
+// remoteFileName returns the name of the file served at rawurl, from its Content-Disposition:
+// signed URLs give it as a parameter, otherwise the headers of the file are requested. The
+// last element of the URL path is the fallback (when it has an extension).
+func (b *Backuper) remoteFileName(ctx context.Context, rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	cd := u.Query().Get("response-content-disposition")
+	if cd == "" {
+		cd = b.headContentDisposition(ctx, rawurl)
+	}
+	var name string
+	if cd != "" {
+		if _, params, err := mime.ParseMediaType(cd); err == nil {
+			name = params["filename"]
+		}
+	}
+	if name == "" {
+		name = path.Base(u.Path)
+	}
+	name = strings.TrimSpace(path.Base(filepath.ToSlash(name)))
+	if !hasExt(name) {
+		return ""
+	}
+	return name
+}
+
+// headContentDisposition returns the Content-Disposition header of the file served at
+// rawurl (empty when the HEAD request fails)
+func (b *Backuper) headContentDisposition(ctx context.Context, rawurl string) string {
+	if b.Client == nil || b.Client.HTTPClient == nil {
+		return ""
+	}
+	req, err := http.NewRequest("HEAD", rawurl, nil)
+	if err != nil {
+		return ""
+	}
+	res, err := b.Client.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return ""
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return ""
+	}
+	return res.Header.Get("Content-Disposition")
+}
+
+// hasExt reports whether name ends with a file extension (as ".pdf" or ".tar.gz", but
+// not ".2 Intro")
+func hasExt(name string) bool {
+	ext := path.Ext(name)
+	if len(ext) < 2 || len(ext) > 6 || len(ext) == len(name) {
+		return false
+	}
+	for _, r := range ext[1:] {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// allocatePaths gives a distinct local path to all the assets. The paths are compared
+// case-insensitively (as on the macOS and Windows file systems). When several assets get
+// the same path, all but the first one are renamed as "name (2).ext", "name (3).ext"...:
+// the course files come first, then the files of the curriculum items (in the order of
+// their IDs, so that each rerun gives the same names), and the downloaded files (whose
+// names come from Udemy) last. The names are truncated again to make room for the suffix.
+//
+// The curriculum items already have distinct prefixes (see uniquePrefix): this mostly
+// renames the downloaded files of a lecture.
+func (b *Backuper) allocatePaths(assets []Asset) {
+	groups := make(map[string][]int)
+	var collisions []string
+	for i, a := range assets {
+		k := strings.ToLower(a.LocalPath)
+		if len(groups[k]) == 1 {
+			collisions = append(collisions, k)
+		}
+		groups[k] = append(groups[k], i)
+	}
+
+	for _, k := range collisions {
+		g := groups[k]
+		sort.SliceStable(g, func(i, j int) bool {
+			a, b := assets[g[i]], assets[g[j]]
+			if (a.LectureID == 0) != (b.LectureID == 0) {
+				return a.LectureID == 0 // <- the course files first
+			}
+			if (a.Type == AssetFile) != (b.Type == AssetFile) {
+				return b.Type == AssetFile // <- the generated files first
+			}
+			if a.LectureID != b.LectureID {
+				return a.LectureID < b.LectureID
+			}
+			return a.AssetID < b.AssetID // <- stable: the files keep their API order
+		})
+		for n, i := range g[1:] {
+			dir, name := filepath.Split(assets[i].LocalPath)
+			ext := ""
+			if hasExt(name) {
+				ext = path.Ext(name) // <- kept by truncateName
+			}
+			max := b.paths().maxNameIn(b.RootDir, dir, 0)
+			for suffix := n + 2; ; suffix++ {
+				s := fmt.Sprintf(" (%d)", suffix)
+				newName := strings.TrimSuffix(truncateName(name, max-len(s)), ext) + s + ext
+				newPath := filepath.Join(dir, newName)
+				if _, taken := groups[strings.ToLower(newPath)]; !taken {
+					groups[strings.ToLower(newPath)] = []int{i}
+					assets[i].LocalPath = newPath
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // <- registers the decoders of the slide formats
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// pdfImage is an image XObject of a PDF document
+type pdfImage struct {
+	Width, Height int
+	ColorSpace    string
+	Filter        string
+	Decode        string // optional /Decode array
+	Data          []byte
+}
+
+// writeImagesPDF writes a PDF document with one page per image file, each page having
+// the size of its image
+func writeImagesPDF(w io.Writer, files []string) error {
+	if len(files) == 0 {
+		return errors.New("pdf: no images")
+	}
+	pw := &pdfWriter{w: bufio.NewWriter(w)}
+	pw.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	// objects: 1 for the catalog, 2 for the page tree, then 3 per page (page, contents, image)
+	kids := make([]string, len(files))
+	for i := range files {
+		kids[i] = fmt.Sprintf("%d 0 R", 3+3*i)
+	}
+	pw.object("<< /Type /Catalog /Pages 2 0 R >>")
+	pw.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(files)))
+	for i, f := range files {
+		img, err := loadPDFImage(f)
+		if err != nil {
+			return err
+		}
+		id := 3 + 3*i
+		pw.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+			img.Width, img.Height, id+2, id+1))
+		pw.stream("", []byte(fmt.Sprintf("q %d 0 0 %d 0 0 cm /Im0 Do Q", img.Width, img.Height)))
+		dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s",
+			img.Width, img.Height, img.ColorSpace, img.Filter)
+		if img.Decode != "" {
+			dict += " /Decode " + img.Decode
+		}
+		pw.stream(dict, img.Data)
+	}
+
+	// cross-reference table
+	xref := pw.n
+	pw.printf("xref\n0 %d\n0000000000 65535 f \n", len(pw.offsets)+1)
+	for _, off := range pw.offsets {
+		pw.printf("%010d 00000 n \n", off)
+	}
+	pw.printf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(pw.offsets)+1, xref)
+	if pw.err != nil {
+		return pw.err
+	}
+	return pw.w.Flush()
+}
+
+// loadPDFImage loads an image file: JPEG images are embedded as is, other formats are
+// converted to compressed RGB
+func loadPDFImage(name string) (*pdfImage, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if format == "jpeg" {
+		img := &pdfImage{Width: cfg.Width, Height: cfg.Height, ColorSpace: "DeviceRGB", Filter: "DCTDecode", Data: data}
+		switch cfg.ColorModel {
+		case color.GrayModel:
+			img.ColorSpace = "DeviceGray"
+		case color.CMYKModel:
+			img.ColorSpace = "DeviceCMYK"
+			if hasAdobeMarker(data) {
+				img.Decode = "[1 0 1 0 1 0 1 0]" // <- Adobe CMYK JPEGs are inverted
+			}
+		}
+		return img, nil
+	}
+
+	m, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	bounds := m.Bounds()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	row := make([]byte, 0, 3*bounds.Dx())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row = row[:0]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+			// transparent pixels are rendered on a white background
+			row = append(row, blendWhite(c.R, c.A), blendWhite(c.G, c.A), blendWhite(c.B, c.A))
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return &pdfImage{Width: bounds.Dx(), Height: bounds.Dy(), ColorSpace: "DeviceRGB", Filter: "FlateDecode", Data: buf.Bytes()}, nil
+}
+
+// hasAdobeMarker reports whether a JPEG file has an Adobe (APP14) segment, before its
+// image data
+func hasAdobeMarker(data []byte) bool {
+	i := 2 // <- after SOI
+	for i+4 <= len(data) {
+		if data[i] != 0xff {
+			return false
+		}
+		marker := data[i+1]
+		if marker == 0xff {
+			i++ // <- fill byte
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 {
+			return false // <- start of scan, or end of image
+		}
+		n := int(data[i+2])<<8 | int(data[i+3])
+		if marker == 0xee && n >= 7 && i+4+5 <= len(data) && string(data[i+4:i+9]) == "Adobe" {
+			return true
+		}
+		i += 2 + n
+	}
+	return false
+}
+
+func blendWhite(v, alpha uint8) uint8 {
+	return uint8((uint32(v)*uint32(alpha) + 255*(255-uint32(alpha))) / 255)
+}
+
+// pdfWriter writes numbered PDF objects, keeping track of their offsets
+type pdfWriter struct {
+	w       *bufio.Writer
+	n       int64   // bytes written so far
+	offsets []int64 // of the objects, in order
+	err     error
+}
+
+func (pw *pdfWriter) printf(format string, args ...interface{}) {
+	pw.write([]byte(fmt.Sprintf(format, args...)))
+}
+
+func (pw *pdfWriter) write(p []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(p)
+	pw.n += int64(n)
+	pw.err = err
+}
+
+func (pw *pdfWriter) object(body string) {
+	pw.offsets = append(pw.offsets, pw.n)
+	pw.printf("%d 0 obj\n%s\nendobj\n", len(pw.offsets), body)
+}
+
+func (pw *pdfWriter) stream(dict string, data []byte) {
+	pw.offsets = append(pw.offsets, pw.n)
+	if dict != "" {
+		dict += " "
+	}
+	pw.printf("%d 0 obj\n<< %s/Length %d >>\nstream\n", len(pw.offsets), dict, len(data))
+	pw.write(data)
+	pw.printf("\nendstream\nendobj\n")
+}
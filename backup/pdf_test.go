@@ -0,0 +1,128 @@
+package backup
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestWriteImagesPDF(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a 2x1 PNG with a transparent pixel, and a 3x2 grayscale JPEG
+	rgba := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	rgba.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	rgba.Set(1, 0, color.NRGBA{})
+	var pngData, jpegData bytes.Buffer
+	if err := png.Encode(&pngData, rgba); err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(&jpegData, image.NewGray(image.Rect(0, 0, 3, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{filepath.Join(dir, "1.png"), filepath.Join(dir, "2.jpg")}
+	_ = ioutil.WriteFile(files[0], pngData.Bytes(), 0644)
+	_ = ioutil.WriteFile(files[1], jpegData.Bytes(), 0644)
+
+	var buf bytes.Buffer
+	if err := writeImagesPDF(&buf, files); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.Bytes()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	for _, want := range []string{
+		"/Count 2",
+		"/MediaBox [0 0 2 1]",
+		"/MediaBox [0 0 3 2]",
+		"/Width 2 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode",
+		"/Width 3 /Height 2 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode",
+	} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("missing %q", want)
+		}
+	}
+
+	// the cross-reference table points to the 8 objects
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n0 9\n")) {
+		t.Fatalf("startxref %d does not point to the xref table", xref)
+	}
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	if len(offsets) != 8 {
+		t.Fatalf("%d objects in the xref table, want 8", len(offsets))
+	}
+	for i, o := range offsets {
+		off, _ := strconv.Atoi(string(o[1]))
+		if !bytes.HasPrefix(pdf[off:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))) {
+			t.Errorf("object %d is not at offset %d", i+1, off)
+		}
+	}
+
+	// the JPEG is embedded as is, and the PNG as RGB on a white background
+	if !bytes.Contains(pdf, jpegData.Bytes()) {
+		t.Error("the JPEG data is not embedded")
+	}
+	img, err := loadPDFImage(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rgb, _ := ioutil.ReadAll(zr); !bytes.Equal(rgb, []byte{255, 0, 0, 255, 255, 255}) {
+		t.Errorf("RGB data = %v", rgb)
+	}
+
+	if err := writeImagesPDF(ioutil.Discard, nil); err == nil {
+		t.Error("expected an error without images")
+	}
+	notImage := filepath.Join(dir, "3.png")
+	_ = ioutil.WriteFile(notImage, []byte("not an image"), 0644)
+	if err := writeImagesPDF(ioutil.Discard, []string{notImage}); err == nil {
+		t.Error("expected an error for an invalid image")
+	}
+}
+
+func TestHasAdobeMarker(t *testing.T) {
+	soi := []byte{0xff, 0xd8}
+	app14 := append([]byte{0xff, 0xee, 0, 14}, []byte("Adobe\x00\x64\x00\x00\x00\x00\x02")...)
+	app0 := append([]byte{0xff, 0xe0, 0, 16}, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")...)
+	sos := []byte{0xff, 0xda, 0, 2}
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"adobe", bytes.Join([][]byte{soi, app14, sos}, nil), true},
+		{"after JFIF", bytes.Join([][]byte{soi, app0, {0xff}, app14, sos}, nil), true}, // <- with a fill byte
+		{"JFIF only", bytes.Join([][]byte{soi, app0, sos}, nil), false},
+		{"after the scan", bytes.Join([][]byte{soi, sos, app14}, nil), false},
+		{"truncated", bytes.Join([][]byte{soi, app14[:6]}, nil), false},
+	}
+	for _, tt := range tests {
+		if got := hasAdobeMarker(tt.data); got != tt.want {
+			t.Errorf("%s: hasAdobeMarker() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+// PlayerFileName is the name of the offline player, at the course root
+const PlayerFileName = "index.html"
+
+// playerCourse is the data of the offline player
+type playerCourse struct {
+	ID       int             `json:"id"`
+	Title    string          `json:"title"`
+	Chapters []playerChapter `json:"chapters"`
+}
+
+type playerChapter struct {
+	Title string       `json:"title"`
+	Items []playerItem `json:"items"`
+}
+
+type playerItem struct {
+	ID        int             `json:"id"`
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Media     string          `json:"media,omitempty"` // video (or audio) file
+	Audio     bool            `json:"audio,omitempty"`
+	Captions  []playerCaption `json:"captions,omitempty"`
+	Documents []playerLink    `json:"documents,omitempty"` // local files
+	Links     []playerLink    `json:"links,omitempty"`     // external links
+}
+
+type playerCaption struct {
+	Label string `json:"label"`
+	Lang  string `json:"lang"`
+	Text  string `json:"text"` // WebVTT contents, as browsers won't load tracks from file:// URLs
+
+	path string
+}
+
+type playerLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// ListPlayerAssets returns the offline player of the course: an "index.html" page at the
+// course root, listing the curriculum and playing the downloaded videos.
+// The page is built once all the other assets are downloaded, to embed the captions, and
+// is refreshed on each backup.
+func (b *Backuper) ListPlayerAssets(course *client.Course, curriculum []interface{}, assets []Asset) []Asset {
+	courseDir := b.getCourseDirectory(course)
+
+	// group the assets by curriculum item
+	type itemKey struct {
+		lecture bool
+		id      int
+	}
+	byItem := make(map[itemKey][]Asset)
+	for _, a := range assets {
+		k := itemKey{true, a.LectureID}
+		switch a.Type {
+		case AssetCourse:
+			continue
+		case AssetQuiz, AssetExercise, AssetPractice:
+			k.lecture = false
+		}
+		byItem[k] = append(byItem[k], a)
+	}
+
+	data := &playerCourse{ID: course.ID, Title: course.Title}
+	var current *playerChapter
+	add := func(item playerItem) {
+		if current == nil {
+			data.Chapters = append(data.Chapters, playerChapter{})
+			current = &data.Chapters[len(data.Chapters)-1]
+		}
+		current.Items = append(current.Items, item)
+	}
+	for _, ci := range curriculum {
+		switch i := ci.(type) {
+		case *client.Chapter:
+			data.Chapters = append(data.Chapters, playerChapter{Title: i.Title})
+			current = &data.Chapters[len(data.Chapters)-1]
+		case *client.Lecture:
+			item := playerItem{ID: i.ID, Type: "lecture", Title: i.Title}
+			for _, a := range byItem[itemKey{true, i.ID}] {
+				addPlayerAsset(&item, courseDir, a)
+			}
+			for _, a := range i.SupplementaryAssets {
+				if a.AssetType == "ExternalLink" {
+					item.Links = append(item.Links, playerLink{a.Title, a.ExternalURL})
+				}
+			}
+			add(item)
+		case *client.Quiz:
+			item := playerItem{ID: i.ID, Type: "quiz", Title: i.Title}
+			for _, a := range byItem[itemKey{false, i.ID}] {
+				addPlayerAsset(&item, courseDir, a)
+			}
+			add(item)
+		case *client.Practice:
+			item := playerItem{ID: i.ID, Type: "practice", Title: i.Title}
+			for _, a := range byItem[itemKey{false, i.ID}] {
+				addPlayerAsset(&item, courseDir, a)
+			}
+			add(item)
+		}
+	}
+
+	return []Asset{{
+		LocalPath: filepath.Join(courseDir, PlayerFileName),
+		Type:      AssetCourse,
+		CourseID:  course.ID,
+		Refresh:   true, // <- new lectures and captions get listed
+		Build: func(_ context.Context, w io.Writer) error {
+			return writePlayer(w, data)
+		},
+	}}
+}
+
+// addPlayerAsset adds a downloaded file to the player item
+func addPlayerAsset(item *playerItem, courseDir string, a Asset) {
+	rel, err := filepath.Rel(courseDir, a.LocalPath)
+	if err != nil {
+		return
+	}
+	u := (&url.URL{Path: filepath.ToSlash(rel)}).String()
+	ext := strings.ToLower(filepath.Ext(a.LocalPath))
+	switch a.Type {
+	case AssetVideo:
+		item.Media = u
+	case AssetAudio:
+		if item.Media == "" {
+			item.Media, item.Audio = u, true
+		}
+	case AssetCaption:
+		for _, c := range item.Captions {
+			if c.Label == a.Variant {
+				return // <- the same caption in another format
+			}
+		}
+		if ext == ".vtt" || ext == ".srt" {
+			lang := a.Variant
+			if i := strings.IndexAny(lang, "_-"); i > 0 {
+				lang = lang[:i]
+			}
+			item.Captions = append(item.Captions, playerCaption{Label: a.Variant, Lang: lang, path: a.LocalPath})
+		}
+	case AssetFile, AssetArticle, AssetQuiz, AssetExercise, AssetPractice, AssetSlide:
+		switch {
+		case a.Type == AssetArticle && ext != ".html",
+			a.Type == AssetQuiz && ext != ".md",
+			a.Type == AssetExercise && filepath.Base(a.LocalPath) != "README.md",
+			a.Type == AssetSlide && ext != ".pdf":
+			return // <- only list the main documents
+		}
+		item.Documents = append(item.Documents, playerLink{filepath.Base(a.LocalPath), u})
+	}
+}
+
+var srtTimestampRegexp = regexp.MustCompile(`(\d\d:\d\d:\d\d),(\d\d\d)`)
+
+// writePlayer writes the offline player page, embedding the downloaded captions
+func writePlayer(w io.Writer, data *playerCourse) error {
+	for i := range data.Chapters {
+		for j := range data.Chapters[i].Items {
+			item := &data.Chapters[i].Items[j]
+			captions := item.Captions[:0]
+			for _, c := range item.Captions {
+				text, err := ioutil.ReadFile(c.path)
+				if err != nil {
+					continue // <- failed download
+				}
+				c.Text = string(text)
+				if filepath.Ext(c.path) == ".srt" {
+					c.Text = "WEBVTT\n\n" + srtTimestampRegexp.ReplaceAllString(c.Text, "$1.$2")
+				}
+				captions = append(captions, c)
+			}
+			item.Captions = captions
+		}
+	}
+
+	var page bytes.Buffer
+	if err := playerTemplate.Execute(&page, data); err != nil {
+		return err
+	}
+	_, err := page.WriteTo(w)
+	return err
+}
+
+var playerTemplate = template.Must(template.New("player").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+body { margin: 0; display: flex; height: 100vh; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #29303b; }
+nav { width: 22em; overflow: auto; border-right: 1px solid #dedfe0; background: #f7f8fa; }
+nav h1 { font-size: 1.1em; margin: 1em; }
+nav h2 { font-size: 0.9em; margin: 1.5em 1em 0.5em; color: #686f7a; }
+nav ol { list-style: none; margin: 0; padding: 0; }
+nav li { padding: 0.5em 1em; cursor: pointer; }
+nav li:hover { background: #e8e9eb; }
+nav li.current { background: #dedfe0; font-weight: bold; }
+nav li.done::before { content: "\2713  "; color: #007791; }
+main { flex: 1; overflow: auto; padding: 1em 2em; }
+video, audio { width: 100%; max-height: 75vh; background: #000; }
+audio { background: none; }
+</style>
+</head>
+<body>
+<nav>
+<h1>{{ .Title }}</h1>
+<div id="curriculum"></div>
+</nav>
+<main>
+<h2 id="title"></h2>
+<div id="player"></div>
+<div id="documents"></div>
+<div id="links"></div>
+</main>
+<script>
+var course = {{ . }};
+var storageKey = "udemy-backup:" + course.id;
+
+function loadProgress() {
+  try { return JSON.parse(localStorage.getItem(storageKey)) || {}; } catch (e) { return {}; }
+}
+function saveProgress(p) {
+  try { localStorage.setItem(storageKey, JSON.stringify(p)); } catch (e) {}
+}
+var progress = loadProgress();
+progress.items = progress.items || {};
+
+function el(tag, text) {
+  var e = document.createElement(tag);
+  if (text) e.textContent = text;
+  return e;
+}
+function key(item) { return item.type + "-" + item.id; }
+
+function linkList(title, links) {
+  var div = el("div");
+  if (!links || !links.length) return div;
+  div.appendChild(el("h3", title));
+  var ul = el("ul");
+  links.forEach(function (l) {
+    var li = el("li"), a = el("a", l.title);
+    a.href = l.url;
+    a.target = "_blank";
+    li.appendChild(a);
+    ul.appendChild(li);
+  });
+  div.appendChild(ul);
+  return div;
+}
+
+var entries = [];
+function show(index) {
+  var item = entries[index].item;
+  entries.forEach(function (e, i) { e.li.classList.toggle("current", i === index); });
+  progress.current = key(item);
+  saveProgress(progress);
+
+  document.getElementById("title").textContent = item.title;
+  var player = document.getElementById("player");
+  player.innerHTML = "";
+  if (item.media) {
+    var media = el(item.audio ? "audio" : "video");
+    media.controls = true;
+    media.src = item.media;
+    (item.captions || []).forEach(function (c, i) {
+      var track = el("track");
+      track.kind = "subtitles";
+      track.label = c.label;
+      track.srclang = c.lang;
+      track.src = URL.createObjectURL(new Blob([c.text], { type: "text/vtt" }));
+      if (i === 0) track.default = true;
+      media.appendChild(track);
+    });
+    var state = progress.items[key(item)] || {};
+    media.addEventListener("loadedmetadata", function () {
+      if (state.time && state.time < media.duration - 5) media.currentTime = state.time;
+    });
+    var last = 0;
+    media.addEventListener("timeupdate", function () {
+      if (Math.abs(media.currentTime - last) < 5) return;
+      last = media.currentTime;
+      state.time = media.currentTime;
+      if (media.duration && media.currentTime > 0.9 * media.duration) state.done = true;
+      progress.items[key(item)] = state;
+      saveProgress(progress);
+      entries[index].li.classList.toggle("done", !!state.done);
+    });
+    media.addEventListener("ended", function () {
+      state.done = true;
+      state.time = 0;
+      progress.items[key(item)] = state;
+      saveProgress(progress);
+      entries[index].li.classList.add("done");
+      if (index + 1 < entries.length) show(index + 1);
+    });
+    player.appendChild(media);
+  }
+  var docs = document.getElementById("documents");
+  docs.innerHTML = "";
+  docs.appendChild(linkList("Files", item.documents));
+  var links = document.getElementById("links");
+  links.innerHTML = "";
+  links.appendChild(linkList("Links", item.links));
+}
+
+var curriculum = document.getElementById("curriculum");
+course.chapters.forEach(function (chapter) {
+  if (chapter.title) curriculum.appendChild(el("h2", chapter.title));
+  var ol = el("ol");
+  (chapter.items || []).forEach(function (item) {
+    var index = entries.length;
+    var li = el("li", item.title);
+    if ((progress.items[key(item)] || {}).done) li.classList.add("done");
+    li.addEventListener("click", function () { show(index); });
+    ol.appendChild(li);
+    entries.push({ item: item, li: li });
+  });
+  curriculum.appendChild(ol);
+});
+
+if (entries.length) {
+  var start = 0;
+  entries.forEach(function (e, i) { if (key(e.item) === progress.current) start = i; });
+  show(start);
+}
+</script>
+</body>
+</html>
+`))
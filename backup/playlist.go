@@ -0,0 +1,190 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+const (
+	// PlaylistFileName is the M3U playlist written at the course root, and in each chapter
+	PlaylistFileName = "playlist.m3u8"
+	// CueFileName is the CUE sheet of the audio-only backups, where each lecture is a track
+	CueFileName = "course.cue"
+	// ChaptersFileName is the ffmetadata chapter list of the course, as if all the lectures
+	// were joined into a single file
+	ChaptersFileName = "chapters.ffmetadata"
+)
+
+// playlistItem is a media file of the playlists
+type playlistItem struct {
+	path     string
+	title    string
+	duration int // in seconds, 0 if unknown
+}
+
+// ListPlaylistAssets returns the playlists of the course, listing the videos (or the audio
+// files) in curriculum order: a "playlist.m3u8" file at the course root and in each
+// chapter, a CUE sheet for the audio files, and a ffmetadata chapter list when all the
+// durations are known.
+// Like the player, the playlists are built once the media files (and the audio extracted
+// from the videos) are written, and are refreshed on each backup.
+func (b *Backuper) ListPlaylistAssets(course *client.Course, curriculum []interface{}, assets []Asset) []Asset {
+	media := make(map[int]Asset) // <- by lecture ID: the video, else the audio
+	for _, a := range assets {
+		switch a.Type {
+		case AssetVideo:
+			media[a.LectureID] = a
+		case AssetAudio:
+			if _, ok := media[a.LectureID]; !ok {
+				media[a.LectureID] = a
+			}
+		}
+	}
+
+	courseDir := b.getCourseDirectory(course)
+	var items []playlistItem
+	var chapterDirs []string
+	chapterItems := make(map[string][]playlistItem)
+	chapterDir := ""
+	for _, item := range curriculum {
+		switch i := item.(type) {
+		case *client.Chapter:
+			chapterDir = b.getChapterDirectory(course, i)
+			chapterDirs = append(chapterDirs, chapterDir)
+		case *client.Lecture:
+			a, ok := media[i.ID]
+			if !ok {
+				continue
+			}
+			it := playlistItem{path: a.LocalPath, title: i.Title}
+			if i.Asset != nil {
+				it.duration = i.Asset.TimeEstimation
+			}
+			items = append(items, it)
+			if chapterDir != "" {
+				chapterItems[chapterDir] = append(chapterItems[chapterDir], it)
+			}
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	var playlists []Asset
+	add := func(path string, write func(w io.Writer, dir string, items []playlistItem) error, items []playlistItem) {
+		dir := filepath.Dir(path)
+		playlists = append(playlists, Asset{
+			LocalPath: path,
+			Type:      AssetCourse,
+			CourseID:  course.ID,
+			Variant:   courseVariant(courseDir, path), // <- a playlist per chapter
+			Refresh:   true,                           // <- new lectures get listed
+			Build: func(_ context.Context, w io.Writer) error {
+				return write(w, dir, downloadedItems(items))
+			},
+		})
+	}
+	add(filepath.Join(courseDir, PlaylistFileName), writeM3U, items)
+	for _, dir := range chapterDirs {
+		if len(chapterItems[dir]) > 0 {
+			add(filepath.Join(dir, PlaylistFileName), writeM3U, chapterItems[dir])
+		}
+	}
+	if b.AudioOnly {
+		add(filepath.Join(courseDir, CueFileName), func(w io.Writer, dir string, items []playlistItem) error {
+			return writeCUE(w, dir, course.Title, items)
+		}, items)
+	}
+	for _, it := range items {
+		if it.duration <= 0 {
+			return playlists // <- the chapters cannot be placed
+		}
+	}
+	add(filepath.Join(courseDir, ChaptersFileName), func(w io.Writer, _ string, items []playlistItem) error {
+		return writeFFMetadata(w, course.Title, items)
+	}, items)
+	return playlists
+}
+
+// downloadedItems drops the items whose file is missing (as failed downloads)
+func downloadedItems(items []playlistItem) []playlistItem {
+	var found []playlistItem
+	for _, it := range items {
+		if fileExists(it.path) {
+			found = append(found, it)
+		}
+	}
+	return found
+}
+
+// playlistPath returns the path of a media file, relative to the playlist directory
+func playlistPath(dir, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
+
+// writeM3U writes an extended M3U playlist
+func writeM3U(w io.Writer, dir string, items []playlistItem) error {
+	if _, err := fmt.Fprintln(w, "#EXTM3U"); err != nil {
+		return err
+	}
+	for _, it := range items {
+		duration := it.duration
+		if duration <= 0 {
+			duration = -1 // <- unknown
+		}
+		title := strings.Join(strings.Fields(it.title), " ")
+		if _, err := fmt.Fprintf(w, "#EXTINF:%d,%s\n%s\n", duration, title, playlistPath(dir, it.path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var cueReplacer = strings.NewReplacer(`"`, "'", "\n", " ", "\r", "")
+
+// writeCUE writes a CUE sheet of audio files, with a file (and track) per lecture
+func writeCUE(w io.Writer, dir, title string, items []playlistItem) error {
+	if _, err := fmt.Fprintf(w, "TITLE \"%s\"\n", cueReplacer.Replace(title)); err != nil {
+		return err
+	}
+	for i, it := range items {
+		typ := "WAVE" // <- used by the players for all the compressed formats they decode
+		if strings.EqualFold(filepath.Ext(it.path), ".mp3") {
+			typ = "MP3"
+		}
+		_, err := fmt.Fprintf(w, "FILE \"%s\" %s\n  TRACK %02d AUDIO\n    TITLE \"%s\"\n    INDEX 01 00:00:00\n",
+			cueReplacer.Replace(playlistPath(dir, it.path)), typ, i+1, cueReplacer.Replace(it.title))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var ffmetadataReplacer = strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+
+// writeFFMetadata writes the chapters of the lectures in the ffmetadata format, as if they
+// were joined in curriculum order (the durations are the estimations given by Udemy)
+func writeFFMetadata(w io.Writer, title string, items []playlistItem) error {
+	if _, err := fmt.Fprintf(w, ";FFMETADATA1\ntitle=%s\n", ffmetadataReplacer.Replace(title)); err != nil {
+		return err
+	}
+	start := 0
+	for _, it := range items {
+		end := start + it.duration
+		_, err := fmt.Fprintf(w, "\n[CHAPTER]\nTIMEBASE=1/1\nSTART=%d\nEND=%d\ntitle=%s\n", start, end, ffmetadataReplacer.Replace(it.title))
+		if err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package backup
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+func TestListPlaylistAssets(t *testing.T) {
+	b := &Backuper{RootDir: "backups", CourseDir: filepath.Join("backups", "course"), AudioOnly: true}
+	course := &client.Course{ID: 1, Title: "Course"}
+	chap1 := &client.Chapter{ID: 10, ObjectIndex: 1, Title: "Basics"}
+	chap2 := &client.Chapter{ID: 11, ObjectIndex: 2, Title: "Advanced"}
+	lecture := func(id int, chap *client.Chapter) *client.Lecture {
+		return &client.Lecture{ID: id, Title: "Lecture", Chapter: chap, Asset: &client.Asset{TimeEstimation: 60}}
+	}
+	curriculum := []interface{}{chap1, lecture(1, chap1), chap2, lecture(2, chap2)}
+	media := []Asset{
+		{LocalPath: filepath.Join(b.getChapterDirectory(course, chap1), "1. Lecture.aac"), Type: AssetAudio, LectureID: 1},
+		{LocalPath: filepath.Join(b.getChapterDirectory(course, chap2), "2. Lecture.aac"), Type: AssetAudio, LectureID: 2},
+	}
+
+	playlists := b.ListPlaylistAssets(course, curriculum, media)
+	if len(playlists) != 5 {
+		t.Fatalf("%d playlists, want 3 M3U8, the CUE sheet and the chapters", len(playlists))
+	}
+	keys := make(map[string]string)
+	for _, a := range playlists {
+		if a.Build == nil || !a.Refresh {
+			t.Errorf("%q: must be built after the audio files", a.LocalPath)
+		}
+		k := a.Key()
+		if p, ok := keys[k]; ok {
+			t.Errorf("%q and %q share the key %q", p, a.LocalPath, k)
+		}
+		keys[k] = a.LocalPath
+	}
+}
@@ -0,0 +1,167 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ushu/udemy-backup/backup/audio"
+	"github.com/ushu/udemy-backup/backup/resolution"
+	"github.com/ushu/udemy-backup/client"
+)
+
+// genre written in the tags of the audio files
+const podcastGenre = "Podcast"
+
+// listPodcastAssets returns the audio track of a lecture, for the audio-only mode: the
+// audio rendition when Udemy provides one, else the AAC track extracted from the video.
+// The files are tagged with the course, chapter and lecture.
+func (b *Backuper) listPodcastAssets(course *client.Course, lecture *client.Lecture, chapDir, prefix string) []Asset {
+	tags := lectureTags(b.courseDetails(course), lecture)
+	a := Asset{
+		Type:    AssetAudio,
+		AssetID: lecture.Asset.ID,
+	}
+
+	var url string
+	videos := findVideos(lecture)
+	if track := findAudio(lecture, videos); track != nil {
+		if ext := audioExt(track.Type); ext != ".m4a" {
+			url := track.File
+			a.LocalPath = filepath.Join(chapDir, prefix+ext)
+			a.Build = func(ctx context.Context, w io.Writer) error {
+				return b.tagAudio(ctx, w, url, tags)
+			}
+			return []Asset{a}
+		}
+		url = track.File // <- MP4 audio: extracted like the videos
+	} else if video, _ := filterVideos(videos, 0, resolution.Lowest); video != nil && video.Type == "video/mp4" {
+		url = video.File // <- the audio track is the same for all the resolutions
+	} else {
+		if lecture.Asset.AssetType == "Video" {
+			b.warn(fmt.Errorf("%s: no audio track can be extracted, skipping the lecture", lecture.Title))
+		}
+		return nil
+	}
+
+	localPath := filepath.Join(chapDir, prefix+".aac")
+	a.LocalPath = localPath
+	a.Build = func(ctx context.Context, w io.Writer) error {
+		return b.extractAudio(ctx, w, url, localPath+".mp4", tags)
+	}
+	return []Asset{a}
+}
+
+// lectureTags returns the audio tags of a lecture
+func lectureTags(course *client.Course, lecture *client.Lecture) *audio.Tags {
+	tags := &audio.Tags{
+		Title: lecture.Title,
+		Album: course.Title,
+		Genre: podcastGenre,
+		Track: lecture.ObjectIndex,
+	}
+	if lecture.Chapter != nil {
+		tags.Group = lecture.Chapter.Title
+		tags.Disc = lecture.Chapter.ObjectIndex
+	}
+	var instructors []string
+	for _, u := range course.Instructors {
+		if u != nil && u.Title != "" {
+			instructors = append(instructors, u.Title)
+		}
+	}
+	tags.Artist = strings.Join(instructors, ", ")
+	return tags
+}
+
+// tagAudio downloads a MP3 (or ADTS) file, and writes it with new tags
+func (b *Backuper) tagAudio(ctx context.Context, w io.Writer, url string, tags *audio.Tags) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := b.Client.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, res.Status)
+	}
+
+	r := bufio.NewReader(res.Body)
+	if err := audio.SkipID3(r); err != nil { // <- replaced by our tags
+		return err
+	}
+	if _, err := tags.WriteTo(w); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// extractAudio downloads a MP4 file to srcPath, and writes its AAC track with the tags
+// (the MP4 file is removed once done, and the AAC file is renamed by buildFile on success)
+func (b *Backuper) extractAudio(ctx context.Context, w io.Writer, url, srcPath string, tags *audio.Tags) (err error) {
+	if err := downloadURLToFile(ctx, b.Client.HTTPClient, url, srcPath); err != nil {
+		return err // <- the partial download is kept, to be resumed
+	}
+	defer func() {
+		if rerr := os.Remove(srcPath); err == nil {
+			err = rerr
+		}
+	}()
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	s, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tags.WriteTo(w); err != nil {
+		return err
+	}
+	return audio.ExtractAAC(w, f, s.Size())
+}
+
+// findAudio returns the audio rendition of a lecture, if any
+func findAudio(lecture *client.Lecture, videos []*client.Video) *client.Video {
+	if lecture.Asset.DownloadUrls != nil {
+		for _, a := range lecture.Asset.DownloadUrls.Audio {
+			if a.File != "" {
+				return a
+			}
+		}
+	}
+	for _, v := range videos {
+		if strings.HasPrefix(v.Type, "audio/") {
+			return v
+		}
+	}
+	return nil
+}
+
+// audioExt returns the extension of the audio files of the given MIME type
+func audioExt(mimeType string) string {
+	switch strings.ToLower(mimeType) {
+	case "audio/mp4", "audio/x-m4a", "audio/m4a":
+		return ".m4a"
+	case "audio/aac", "audio/aacp", "audio/x-aac":
+		return ".aac"
+	}
+	return ".mp3"
+}
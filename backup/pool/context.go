@@ -0,0 +1,16 @@
+package pool
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a new context carrying p
+func NewContext(ctx context.Context, p *Pool) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the Pool stored in ctx, if any
+func FromContext(ctx context.Context) (*Pool, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Pool)
+	return p, ok
+}
@@ -0,0 +1,244 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrStopped is returned when enqueuing work on a pool that is not accepting jobs anymore
+var ErrStopped = errors.New("pool: stopped")
+
+// Func is the work performed by a Job
+type Func func(ctx context.Context) error
+
+// Job is a unit of work run by the Pool
+type Job struct {
+	Name       string
+	Func       Func
+	RetryCount int // number of additional attempts when Func fails
+
+	done     chan struct{}
+	err      error
+	attempts int
+}
+
+// NewJob creates a Job with the given name, that won't be retried on failure
+func NewJob(name string, fn Func) *Job {
+	return &Job{Name: name, Func: fn}
+}
+
+// Wait blocks until the job has completed (successfully or not) and returns its error
+func (j *Job) Wait() error {
+	<-j.done
+	return j.err
+}
+
+// Err returns the error of a completed job
+func (j *Job) Err() error {
+	select {
+	case <-j.done:
+		return j.err
+	default:
+		return nil
+	}
+}
+
+// Attempts returns the number of times a completed job has been run
+func (j *Job) Attempts() int {
+	return j.attempts
+}
+
+func (j *Job) finish(err error) {
+	j.err = err
+	close(j.done)
+}
+
+// Pool runs jobs in parallel on a fixed number of workers.
+//
+// Producers enqueue jobs (possibly while the pool is already running), then call Done
+// once there is no more work: Start returns when all the enqueued jobs have completed.
+type Pool struct {
+	NumWorkers int
+	RetryCount int           // default number of retries for jobs enqueued with Enqueue
+	RetryDelay time.Duration // delay between two attempts of a failing job
+
+	jobs     chan *Job
+	closed   chan struct{} // <- closed by Done
+	stopped  chan struct{}
+	doneOnce sync.Once
+	stopOnce sync.Once
+
+	mu     sync.Mutex
+	errors Errors
+}
+
+// New creates a pool running numWorkers jobs in parallel
+func New(numWorkers int) *Pool {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	return &Pool{
+		NumWorkers: numWorkers,
+		RetryDelay: time.Second,
+		jobs:       make(chan *Job),
+		closed:     make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+}
+
+// Enqueue adds a new job running fn, retried up to p.RetryCount times
+func (p *Pool) Enqueue(ctx context.Context, name string, fn Func) (*Job, error) {
+	j := &Job{
+		Name:       name,
+		Func:       fn,
+		RetryCount: p.RetryCount,
+	}
+	return j, p.EnqueueJob(ctx, j)
+}
+
+// EnqueueJob adds j to the queue, blocking until a worker picks it up.
+// When the job cannot be enqueued (as after Done), it is marked as completed with the
+// returned error.
+func (p *Pool) EnqueueJob(ctx context.Context, j *Job) error {
+	j.done = make(chan struct{})
+	select {
+	case <-p.closed:
+		j.finish(ErrStopped)
+		return ErrStopped
+	default:
+	}
+	select {
+	case <-ctx.Done():
+		j.finish(ctx.Err())
+		return ctx.Err()
+	case <-p.closed:
+		j.finish(ErrStopped)
+		return ErrStopped
+	case <-p.stopped:
+		j.finish(ErrStopped)
+		return ErrStopped
+	case p.jobs <- j:
+		return nil
+	}
+}
+
+// Done signals that no more jobs will be enqueued
+func (p *Pool) Done() {
+	p.doneOnce.Do(func() {
+		close(p.closed)
+	})
+}
+
+// Start runs the workers, and blocks until Done has been called and all the jobs have
+// completed, or until ctx gets canceled.
+// All the job failures are collected and returned as an Errors value.
+func (p *Pool) Start(ctx context.Context) error {
+	defer p.stopOnce.Do(func() {
+		close(p.stopped)
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(p.NumWorkers)
+	for i := 0; i < p.NumWorkers; i++ {
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.errors) > 0 {
+		return p.errors
+	}
+	return nil
+}
+
+// Errors returns the errors collected so far
+func (p *Pool) Errors() Errors {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append(Errors(nil), p.errors...)
+}
+
+func (p *Pool) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.closed:
+			return // <- Done was called, the queue is empty
+		case j := <-p.jobs:
+			err := p.run(ctx, j)
+			if err != nil {
+				p.mu.Lock()
+				p.errors = append(p.errors, err)
+				p.mu.Unlock()
+			}
+			j.finish(err)
+		}
+	}
+}
+
+func (p *Pool) run(ctx context.Context, j *Job) error {
+	var err error
+	for j.attempts <= j.RetryCount {
+		if j.attempts > 0 && p.RetryDelay > 0 {
+			// wait a bit before retrying
+			select {
+			case <-ctx.Done():
+				return &JobError{j.Name, j.attempts, ctx.Err()}
+			case <-time.After(p.RetryDelay):
+			}
+		}
+		j.attempts++
+		if err = j.Func(ctx); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			break // <- no need to retry on cancelation
+		}
+	}
+	return &JobError{j.Name, j.attempts, err}
+}
+
+// JobError reports the failure of a job after all its attempts
+type JobError struct {
+	Name     string
+	Attempts int
+	Err      error
+}
+
+func (e *JobError) Error() string {
+	if e.Name == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v (after %d attempts)", e.Name, e.Err, e.Attempts)
+}
+
+// Unwrap returns the last error returned by the job
+func (e *JobError) Unwrap() error {
+	return e.Err
+}
+
+// Errors holds all the errors collected by a Pool
+type Errors []error
+
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred:\n\t%s", len(e), strings.Join(msgs, "\n\t"))
+}
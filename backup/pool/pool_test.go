@@ -0,0 +1,112 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPoolRetry(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name      string
+		failures  int32 // <- number of failing attempts before a success
+		retries   int
+		wantErr   bool
+		wantTries int
+	}{
+		{"success", 0, 2, false, 1},
+		{"retried", 2, 2, false, 3},
+		{"failed", 5, 2, true, 3},
+		{"no retry", 1, 0, true, 1},
+	}
+	for _, tt := range tests {
+		p := New(2)
+		p.RetryCount = tt.retries
+		p.RetryDelay = 0
+		var calls int32
+		var job *Job
+		go func() {
+			job, _ = p.Enqueue(context.Background(), tt.name, func(context.Context) error {
+				if atomic.AddInt32(&calls, 1) <= tt.failures {
+					return errFailed
+				}
+				return nil
+			})
+			p.Done()
+		}()
+		err := p.Start(context.Background())
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Start() = %v", tt.name, err)
+		}
+		if err := job.Wait(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Wait() = %v", tt.name, err)
+		} else if err != nil && !errors.Is(err, errFailed) {
+			t.Errorf("%s: %v does not wrap the job error", tt.name, err)
+		}
+		if job.Attempts() != tt.wantTries {
+			t.Errorf("%s: %d attempts, want %d", tt.name, job.Attempts(), tt.wantTries)
+		}
+	}
+}
+
+func TestPoolErrors(t *testing.T) {
+	p := New(3)
+	p.RetryDelay = 0
+	var ran int32
+	go func() {
+		for i := 0; i < 10; i++ {
+			fail := i%3 == 0
+			_, _ = p.Enqueue(context.Background(), "job", func(context.Context) error {
+				atomic.AddInt32(&ran, 1)
+				if fail {
+					return errors.New("failed")
+				}
+				return nil
+			})
+		}
+		p.Done()
+	}()
+	err := p.Start(context.Background())
+	if ran != 10 {
+		t.Errorf("%d jobs ran, want all the 10 jobs despite the failures", ran)
+	}
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 4 {
+		t.Fatalf("Start() = %v, want 4 errors", err)
+	}
+	if len(p.Errors()) != 4 {
+		t.Errorf("Errors() gives %d errors, want 4", len(p.Errors()))
+	}
+}
+
+func TestPoolStopped(t *testing.T) {
+	p := New(1)
+	p.Done()
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	j := NewJob("late", func(context.Context) error { return nil })
+	if err := p.EnqueueJob(context.Background(), j); err != ErrStopped {
+		t.Errorf("EnqueueJob() = %v, want ErrStopped", err)
+	}
+	if err := j.Wait(); err != ErrStopped {
+		t.Errorf("Wait() = %v, want ErrStopped", err)
+	}
+}
+
+func TestPoolCanceled(t *testing.T) {
+	p := New(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, _ = p.Enqueue(ctx, "job", func(ctx context.Context) error {
+			cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+	if err := p.Start(ctx); err != context.Canceled {
+		t.Errorf("Start() = %v, want context.Canceled", err)
+	}
+}
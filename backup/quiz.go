@@ -0,0 +1,169 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ushu/udemy-backup/client"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// quizExport is the JSON representation of a quiz
+type quizExport struct {
+	ID          int              `json:"id"`
+	Title       string           `json:"title"`
+	Type        string           `json:"type"`
+	Description string           `json:"description,omitempty"`
+	Duration    int              `json:"duration,omitempty"`
+	PassPercent int              `json:"pass_percent,omitempty"`
+	Questions   []questionExport `json:"questions"`
+}
+
+type questionExport struct {
+	ID          int            `json:"id"`
+	Type        string         `json:"type"`
+	Section     string         `json:"section,omitempty"`
+	Question    string         `json:"question"`
+	Answers     []answerExport `json:"answers"`
+	Explanation string         `json:"explanation,omitempty"`
+}
+
+type answerExport struct {
+	Text     string `json:"text"`
+	Correct  bool   `json:"correct"`
+	Feedback string `json:"feedback,omitempty"`
+}
+
+// ListQuizAssets loads the questions of a quiz (or practice test), and returns its JSON and
+// Markdown exports
+func (b *Backuper) ListQuizAssets(ctx context.Context, course *client.Course, quiz *client.Quiz) ([]Asset, error) {
+	assessments, err := b.Client.LoadAllAssessments(ctx, quiz.ID)
+	if err != nil {
+		return nil, err
+	}
+	export := newQuizExport(quiz, assessments)
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	chapDir := b.getChapterDirectory(course, quiz.Chapter)
+	prefix := b.getQuizPrefix(course, chapDir, quiz)
+	if quiz.Type == "practice-test" {
+		prefix += ".practice-test"
+	} else {
+		prefix += ".quiz"
+	}
+	assets := []Asset{
+		{
+			LocalPath: filepath.Join(chapDir, prefix+".json"),
+			Contents:  data,
+			Type:      AssetQuiz,
+		},
+		{
+			LocalPath: filepath.Join(chapDir, prefix+".md"),
+			Contents:  quizToMarkdown(export),
+			Type:      AssetQuiz,
+		},
+	}
+
+	// tag all the assets with the quiz they belong to
+	for i := range assets {
+		assets[i].CourseID = course.ID
+		assets[i].LectureID = quiz.ID
+		if quiz.Chapter != nil {
+			assets[i].ChapterID = quiz.Chapter.ID
+		}
+	}
+	return assets, nil
+}
+
+func newQuizExport(quiz *client.Quiz, assessments []*client.Assessment) *quizExport {
+	export := &quizExport{
+		ID:          quiz.ID,
+		Title:       quiz.Title,
+		Type:        quiz.Type,
+		Description: quiz.Description,
+		Duration:    quiz.Duration,
+		PassPercent: quiz.PassPercent,
+		Questions:   make([]questionExport, 0, len(assessments)),
+	}
+	for _, a := range assessments {
+		q := questionExport{
+			ID:      a.ID,
+			Type:    a.AssessmentType,
+			Section: a.Section,
+		}
+		if p := a.Prompt; p != nil {
+			q.Question = p.Question
+			q.Explanation = p.Explanation
+			for i, text := range p.Answers {
+				answer := answerExport{Text: text, Correct: a.IsCorrect(i)}
+				if i < len(p.Feedbacks) {
+					answer.Feedback = p.Feedbacks[i]
+				}
+				q.Answers = append(q.Answers, answer)
+			}
+		}
+		export.Questions = append(export.Questions, q)
+	}
+	return export
+}
+
+// quizToMarkdown writes a study sheet for the quiz, with the correct answers and feedbacks
+func quizToMarkdown(quiz *quizExport) []byte {
+	w := new(bytes.Buffer)
+	fmt.Fprintf(w, "# %s\n\n", quiz.Title)
+	if quiz.Duration > 0 || quiz.PassPercent > 0 {
+		minutes := (quiz.Duration + 59) / 60 // <- rounded up
+		fmt.Fprintf(w, "_Duration: %d minutes, pass mark: %d%%_\n\n", minutes, quiz.PassPercent)
+	}
+	if quiz.Description != "" {
+		fmt.Fprintf(w, "%s\n", fragmentToMarkdown(quiz.Description))
+	}
+	for i, q := range quiz.Questions {
+		fmt.Fprintf(w, "## Question %d\n\n", i+1)
+		if q.Section != "" {
+			fmt.Fprintf(w, "_%s_\n\n", q.Section)
+		}
+		fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(fragmentToMarkdown(q.Question)))
+		for _, a := range q.Answers {
+			check := "[ ]"
+			if a.Correct {
+				check = "[x]"
+			}
+			fmt.Fprintf(w, "- %s %s\n", check, inlineMarkdown(a.Text))
+			if a.Feedback != "" {
+				fmt.Fprintf(w, "  > %s\n", inlineMarkdown(a.Feedback))
+			}
+		}
+		fmt.Fprintln(w)
+		if q.Explanation != "" {
+			fmt.Fprintf(w, "**Explanation:**\n\n%s\n\n", strings.TrimSpace(fragmentToMarkdown(q.Explanation)))
+		}
+	}
+	return w.Bytes()
+}
+
+// fragmentToMarkdown converts an HTML fragment into Markdown
+func fragmentToMarkdown(s string) string {
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(s), root)
+	if err != nil {
+		return s
+	}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	return htmlToMarkdown(root)
+}
+
+// inlineMarkdown converts an HTML fragment into a single line of Markdown
+func inlineMarkdown(s string) string {
+	return strings.Join(strings.Fields(fragmentToMarkdown(s)), " ")
+}
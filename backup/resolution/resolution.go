@@ -0,0 +1,118 @@
+// Package resolution selects the resolution of the videos, given a preferred resolution
+// and a selection policy.
+package resolution
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Policy tells how the resolution is selected among the available ones
+type Policy string
+
+const (
+	Exact   Policy = "exact"   // only the preferred resolution
+	Closest Policy = "closest" // the resolution closest to the preferred one (the highest on ties)
+	AtMost  Policy = "at-most" // the highest resolution at or below the preferred one
+	Lowest  Policy = "lowest"  // the lowest resolution, to save disk space
+	Highest Policy = "highest" // the highest resolution
+)
+
+// Validate checks the policy (empty means Closest)
+func (p Policy) Validate() error {
+	switch p {
+	case "", Exact, Closest, AtMost, Lowest, Highest:
+		return nil
+	}
+	return fmt.Errorf("resolution: invalid policy %q", p)
+}
+
+// ParseLabel returns the height given by a label as "720" or "720p", or 0 for labels
+// without resolution (as "Auto")
+func ParseLabel(label string) int {
+	label = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(label)), "p")
+	h, err := strconv.Atoi(label)
+	if err != nil || h < 0 {
+		return 0
+	}
+	return h
+}
+
+// Select returns the index of the selected height, or -1 when none matches the policy.
+// Unknown heights (0) are only selected when no height is known, except with the Exact
+// and AtMost policies. ok reports whether the preferred resolution was honored (it always
+// is with the Lowest and Highest policies). On ties the first candidate wins. An empty
+// policy means Closest.
+func Select(heights []int, preferred int, p Policy) (index int, ok bool) {
+	if len(heights) == 0 {
+		return -1, false
+	}
+	if p == "" {
+		p = Closest
+	}
+	if preferred <= 0 && p != Lowest {
+		p = Highest // <- no preference
+	}
+	best := -1
+	for i, h := range heights {
+		if h <= 0 {
+			continue
+		}
+		if best < 0 {
+			best = i
+			continue
+		}
+		b := heights[best]
+		switch p {
+		case Exact:
+			if h == preferred && b != preferred {
+				best = i
+			}
+		case Closest:
+			d, bd := distance(h, preferred), distance(b, preferred)
+			if d < bd || (d == bd && h > b) {
+				best = i
+			}
+		case AtMost:
+			if (h <= preferred && (b > preferred || h > b)) || (b > preferred && h < b) {
+				best = i
+			}
+		case Lowest:
+			if h < b {
+				best = i
+			}
+		default:
+			if h > b {
+				best = i
+			}
+		}
+	}
+	if best < 0 {
+		// only unknown resolutions: take the first one, unless the preferred one is required
+		switch p {
+		case Exact, AtMost:
+			return -1, false
+		case Closest:
+			return 0, false
+		}
+		return 0, true
+	}
+	switch p {
+	case Exact:
+		if heights[best] != preferred {
+			return -1, false
+		}
+		return best, true
+	case Closest, AtMost:
+		return best, heights[best] == preferred
+	}
+	return best, true
+}
+
+func distance(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
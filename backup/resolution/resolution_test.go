@@ -0,0 +1,78 @@
+package resolution
+
+import "testing"
+
+func TestParseLabel(t *testing.T) {
+	tests := []struct {
+		label string
+		want  int
+	}{
+		{"720", 720},
+		{"1080p", 1080},
+		{" 480P ", 480},
+		{"Auto", 0},
+		{"-1", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := ParseLabel(tt.label); got != tt.want {
+			t.Errorf("ParseLabel(%q) = %d, want %d", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	heights := []int{360, 720, 1080, 0}
+	tests := []struct {
+		heights   []int
+		preferred int
+		policy    Policy
+		want      int
+		wantOK    bool
+	}{
+		// no preference: the highest
+		{heights, 0, "", 2, true},
+		{heights, 0, Exact, 2, true},
+		{heights, 0, Lowest, 0, true},
+
+		{heights, 720, Exact, 1, true},
+		{heights, 480, Exact, -1, false},
+		{heights, 720, Closest, 1, true},
+		{heights, 480, Closest, 0, false},
+		{heights, 540, "", 1, false}, // <- the highest on ties
+		{heights, 900, AtMost, 1, false},
+		{heights, 1080, AtMost, 2, true},
+		{heights, 240, AtMost, 0, false}, // <- nothing below: the lowest
+		{heights, 720, Lowest, 0, true},
+		{heights, 720, Highest, 2, true},
+
+		// the first one on ties
+		{[]int{720, 720}, 720, Exact, 0, true},
+
+		// only unknown resolutions
+		{[]int{0, 0}, 720, Exact, -1, false},
+		{[]int{0, 0}, 720, AtMost, -1, false},
+		{[]int{0, 0}, 720, Closest, 0, false},
+		{[]int{0, 0}, 720, Highest, 0, true},
+		{[]int{0, 0}, 720, Lowest, 0, true},
+
+		{nil, 720, Closest, -1, false},
+	}
+	for _, tt := range tests {
+		got, ok := Select(tt.heights, tt.preferred, tt.policy)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Select(%v, %d, %q) = %d, %v, want %d, %v", tt.heights, tt.preferred, tt.policy, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	for _, p := range []Policy{"", Exact, Closest, AtMost, Lowest, Highest} {
+		if err := p.Validate(); err != nil {
+			t.Errorf("%q: unexpected error %v", p, err)
+		}
+	}
+	if err := Policy("best").Validate(); err == nil {
+		t.Error("expected an error for an invalid policy")
+	}
+}
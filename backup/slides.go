@@ -0,0 +1,78 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ushu/udemy-backup/client"
+)
+
+// name of the directory receiving the slide images, in the lecture assets directory
+const slidesDir = "slides"
+
+// slideSync is an entry of the slide timings file
+type slideSync struct {
+	Slide int     `json:"slide"` // starting at 1
+	File  string  `json:"file"`  // relative to the timings file
+	Time  float64 `json:"time"`  // in seconds
+}
+
+// listSlideAssets returns the slide images of a presentation, the PDF assembled from
+// them, and the slide timings when the slides are synced with the video
+func listSlideAssets(lecture *client.Lecture, chapDir, prefix string) ([]Asset, string) {
+	dir := filepath.Join(chapDir, prefix, slidesDir)
+	var assets []Asset
+	var images []string
+	var syncs []slideSync
+	for i, s := range lecture.Asset.SlideUrls {
+		if s == nil || s.URL == "" {
+			continue
+		}
+		name := fmt.Sprintf("%03d%s", i+1, imageExt(s.URL))
+		p := filepath.Join(dir, name)
+		images = append(images, p)
+		assets = append(assets, Asset{
+			LocalPath: p,
+			RemoteURL: s.URL,
+			Type:      AssetSlide,
+			AssetID:   lecture.Asset.ID,
+			Variant:   strconv.Itoa(i + 1),
+		})
+		if s.Time >= 0 {
+			syncs = append(syncs, slideSync{
+				Slide: i + 1,
+				File:  path.Join(prefix, slidesDir, name),
+				Time:  s.Time,
+			})
+		}
+	}
+	if len(images) == 0 {
+		return nil, ""
+	}
+
+	// the PDF is built once all the images are downloaded
+	assets = append(assets, Asset{
+		LocalPath: filepath.Join(chapDir, prefix+".pdf"),
+		Type:      AssetSlide,
+		AssetID:   lecture.Asset.ID,
+		Build: func(_ context.Context, w io.Writer) error {
+			return writeImagesPDF(w, images)
+		},
+	})
+	if len(syncs) > 0 {
+		if data, err := json.MarshalIndent(syncs, "", "  "); err == nil {
+			assets = append(assets, Asset{
+				LocalPath: filepath.Join(chapDir, prefix+".slides.json"),
+				Contents:  data,
+				Type:      AssetSlide,
+				AssetID:   lecture.Asset.ID,
+			})
+		}
+	}
+	return assets, dir
+}
@@ -0,0 +1,66 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"github.com/ushu/udemy-backup/backup/captions"
+	"github.com/ushu/udemy-backup/client"
+)
+
+// listCaptionAssets returns the caption files of a lecture, as selected and converted
+// according to b.Captions
+func (b *Backuper) listCaptionAssets(lecture *client.Lecture, dir, prefix string) []Asset {
+	opts := b.Captions
+	var assets []Asset
+	for _, c := range captions.Select(lecture.Asset.Captions, opts) {
+		variant := c.Locale.Locale
+		if opts.Auto == captions.All && captions.IsAuto(c) {
+			variant += ".auto"
+		}
+		formats, raw := opts.Formats(), false
+		if ext := filepath.Ext(c.FileName); ext != "" && ext != ".vtt" {
+			formats, raw = []string{ext}, true // <- not WebVTT: kept as is
+		}
+		for _, ext := range formats {
+			a := Asset{
+				LocalPath: filepath.Join(dir, fmt.Sprintf("%s.%s%s", prefix, variant, ext)),
+				Type:      AssetCaption,
+				AssetID:   lecture.Asset.ID,
+				Variant:   variant,
+			}
+			if raw || (ext == ".vtt" && !opts.Strip) {
+				a.RemoteURL = c.URL // <- kept as served by Udemy
+			} else {
+				url, ext := c.URL, ext
+				a.Build = func(ctx context.Context, w io.Writer) error {
+					return b.convertCaption(ctx, w, url, ext)
+				}
+			}
+			assets = append(assets, a)
+		}
+	}
+	return assets
+}
+
+// convertCaption downloads a WebVTT caption, and writes it in the format given by ext
+func (b *Backuper) convertCaption(ctx context.Context, w io.Writer, url, ext string) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := b.Client.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, res.Status)
+	}
+	return captions.Convert(w, res.Body, ext, b.Captions.Strip)
+}
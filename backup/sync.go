@@ -0,0 +1,130 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ArchiveDirName is the directory (at the root of the course directory) receiving the
+// files of the assets removed from the course
+const ArchiveDirName = ".archive"
+
+// SyncResult lists the changes applied to the course directory by Sync
+type SyncResult struct {
+	Moved    []Asset  // assets whose file got moved to the new path
+	Archived []string // paths (relative to the course directory) of the archived files
+}
+
+// Sync matches the files recorded in the manifest with the assets of the course (by lecture
+// and asset IDs), and moves the files of renamed or reordered lectures to their new path,
+// instead of downloading them again.
+// When archive is set, the files of the assets that are not part of the course anymore are
+// moved into the ".archive" directory.
+func Sync(m *Manifest, courseDir string, assets []Asset, archive bool) (*SyncResult, error) {
+	res := &SyncResult{}
+
+	// index the current assets by key
+	byKey := make(map[string]Asset, len(assets))
+	for _, a := range assets {
+		if a.LectureID != 0 {
+			byKey[a.Key()] = a
+		}
+	}
+
+	// the files already at their path are kept
+	entries := append([]*ManifestEntry(nil), m.Entries...)
+	upToDate := make(map[*ManifestEntry]bool)
+	for _, e := range entries {
+		oldPath := filepath.Join(courseDir, filepath.FromSlash(e.Path))
+		if a, ok := byKey[e.Key()]; ok && e.LectureID != 0 && a.LocalPath == oldPath && fileExists(oldPath) {
+			upToDate[e] = true
+			delete(byKey, e.Key()) // <- other entries with the same key are obsolete
+		}
+	}
+
+	// the files to move are first renamed to temporary names, so that lectures can swap
+	// their paths
+	type move struct {
+		e *ManifestEntry
+		a Asset
+	}
+	var moves []move
+	for _, e := range entries {
+		if e.LectureID == 0 || upToDate[e] {
+			continue // <- not managed by the sync, or up to date
+		}
+		oldPath := filepath.Join(courseDir, filepath.FromSlash(e.Path))
+		if !fileExists(oldPath) {
+			m.Remove(e.Path)
+			continue
+		}
+
+		if a, ok := byKey[e.Key()]; ok {
+			// the lecture has been renamed or moved
+			tmpPath := oldPath + ".sync.tmp"
+			if err := os.Rename(oldPath, tmpPath); err != nil {
+				return res, err
+			}
+			if err := m.rename(courseDir, e, tmpPath); err != nil {
+				return res, err
+			}
+			moves = append(moves, move{e, a})
+			delete(byKey, e.Key()) // <- other entries with the same key are now obsolete
+			continue
+		}
+
+		// the asset is gone
+		if archive {
+			if err := moveFile(oldPath, filepath.Join(courseDir, ArchiveDirName, filepath.FromSlash(e.Path)), courseDir); err != nil {
+				return res, err
+			}
+			m.Remove(e.Path)
+			res.Archived = append(res.Archived, e.Path)
+		}
+	}
+
+	// then moved to their new path, replacing the files left there (that would be
+	// downloaded again anyway)
+	for _, mv := range moves {
+		tmpPath := filepath.Join(courseDir, filepath.FromSlash(mv.e.Path))
+		if err := moveFile(tmpPath, mv.a.LocalPath, courseDir); err != nil {
+			return res, err
+		}
+		if err := m.rename(courseDir, mv.e, mv.a.LocalPath); err != nil {
+			return res, err
+		}
+		res.Moved = append(res.Moved, mv.a)
+	}
+	return res, nil
+}
+
+// rename changes the path of an entry, replacing the entry of the new path (if any)
+func (m *Manifest) rename(courseDir string, e *ManifestEntry, path string) error {
+	rel, err := filepath.Rel(courseDir, path)
+	if err != nil {
+		return err
+	}
+	m.Remove(e.Path)
+	e.Path = filepath.ToSlash(rel)
+	m.add(e)
+	return nil
+}
+
+// moveFile renames oldPath into newPath, creating the missing directories and removing
+// the directories left empty (up to rootDir)
+func moveFile(oldPath, newPath, rootDir string) error {
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	// os.Remove fails on non-empty directories, which stops the cleanup
+	rootDir = filepath.Clean(rootDir)
+	for dir := filepath.Dir(oldPath); dir != rootDir && len(dir) > len(rootDir); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
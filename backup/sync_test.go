@@ -0,0 +1,85 @@
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	courseDir, err := ioutil.TempDir("", "sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(courseDir)
+	path := func(rel string) string {
+		return filepath.Join(courseDir, filepath.FromSlash(rel))
+	}
+
+	// previous backup: lectures 1 and 2 swapped their titles, 3 moved to another chapter,
+	// 4 is unchanged and 5 was removed from the course
+	old := []Asset{
+		{LocalPath: path("1. Intro/1. First.mp4"), Type: AssetVideo, LectureID: 1},
+		{LocalPath: path("1. Intro/2. Second.mp4"), Type: AssetVideo, LectureID: 2},
+		{LocalPath: path("1. Intro/3. Third.mp4"), Type: AssetVideo, LectureID: 3},
+		{LocalPath: path("1. Intro/4. Fourth.mp4"), Type: AssetVideo, LectureID: 4},
+		{LocalPath: path("1. Intro/5. Fifth.mp4"), Type: AssetVideo, LectureID: 5},
+	}
+	m := &Manifest{}
+	for _, a := range old {
+		if err := os.MkdirAll(filepath.Dir(a.LocalPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(a.LocalPath, []byte(filepath.Base(a.LocalPath)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Record(courseDir, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assets := []Asset{
+		{LocalPath: path("1. Intro/1. Second.mp4"), Type: AssetVideo, LectureID: 2},
+		{LocalPath: path("1. Intro/2. First.mp4"), Type: AssetVideo, LectureID: 1},
+		{LocalPath: path("2. More/1. Third.mp4"), Type: AssetVideo, LectureID: 3},
+		{LocalPath: path("1. Intro/4. Fourth.mp4"), Type: AssetVideo, LectureID: 4},
+	}
+	res, err := Sync(m, courseDir, assets, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Moved) != 3 {
+		t.Errorf("%d files moved, want 3", len(res.Moved))
+	}
+	if len(res.Archived) != 1 || res.Archived[0] != "1. Intro/5. Fifth.mp4" {
+		t.Errorf("archived %q, want the fifth lecture", res.Archived)
+	}
+
+	want := map[string]string{
+		"1. Intro/1. Second.mp4":         "2. Second.mp4",
+		"1. Intro/2. First.mp4":          "1. First.mp4",
+		"2. More/1. Third.mp4":           "3. Third.mp4",
+		"1. Intro/4. Fourth.mp4":         "4. Fourth.mp4",
+		".archive/1. Intro/5. Fifth.mp4": "5. Fifth.mp4",
+	}
+	for rel, contents := range want {
+		if got, err := ioutil.ReadFile(path(rel)); err != nil || string(got) != contents {
+			t.Errorf("%s: %q, %v, want the file %q", rel, got, err, contents)
+		}
+	}
+	if fileExists(path("1. Intro/3. Third.mp4")) {
+		t.Error("the moved file is left at its old path")
+	}
+
+	// the manifest follows the files
+	if len(m.Entries) != 4 {
+		t.Errorf("%d entries, want 4", len(m.Entries))
+	}
+	for _, a := range assets {
+		rel, _ := filepath.Rel(courseDir, a.LocalPath)
+		if e := m.Lookup(rel); e == nil || e.LectureID != a.LectureID {
+			t.Errorf("%s: manifest entry %+v, want lecture %d", rel, e, a.LectureID)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package backup
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStatus is the outcome of the verification of a file
+type FileStatus string
+
+const (
+	FileOK        FileStatus = "ok"
+	FileMissing   FileStatus = "missing"   // recorded in the manifest, but not on disk
+	FileTruncated FileStatus = "truncated" // smaller than recorded in the manifest
+	FileModified  FileStatus = "modified"  // SHA-256 or size differ from the manifest
+	FileRemote    FileStatus = "remote"    // recorded size differs from the remote one
+	FileUntracked FileStatus = "untracked" // on disk, but not in the manifest
+)
+
+// VerifyOptions configures Verify
+type VerifyOptions struct {
+	// CheckRemote compares the recorded sizes with the ones reported by the server (except
+	// for the videos joined from HLS streams)
+	CheckRemote bool
+	HTTPClient  *http.Client
+}
+
+// FileReport is the verification report for a file of the course directory
+type FileReport struct {
+	Path       string         // relative to the course directory
+	Entry      *ManifestEntry // nil for untracked files
+	Status     FileStatus
+	Size       int64 // local size
+	RemoteSize int64 // when checked, -1 if unknown
+}
+
+// Broken reports whether the file needs to be downloaded again
+func (r *FileReport) Broken() bool {
+	switch r.Status {
+	case FileMissing, FileTruncated, FileModified, FileRemote:
+		return true
+	}
+	return false
+}
+
+// Verify checks all the files of a course directory against its manifest
+func Verify(ctx context.Context, courseDir string, opts VerifyOptions) (*Manifest, []*FileReport, error) {
+	m, err := LoadManifest(courseDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// check all the recorded files
+	var reports []*FileReport
+	tracked := make(map[string]bool)
+	for _, e := range m.Entries {
+		if err := ctx.Err(); err != nil {
+			return m, reports, err
+		}
+		tracked[e.Path] = true
+		r := &FileReport{Path: e.Path, Entry: e, Status: FileOK, RemoteSize: -1}
+		reports = append(reports, r)
+
+		p := filepath.Join(courseDir, filepath.FromSlash(e.Path))
+		s, err := os.Stat(p)
+		if os.IsNotExist(err) {
+			r.Status = FileMissing
+			continue
+		} else if err != nil {
+			return m, reports, err
+		}
+		r.Size = s.Size()
+		if r.Size < e.Size {
+			r.Status = FileTruncated
+			continue
+		}
+		sum, _, err := hashFile(p)
+		if err != nil {
+			return m, reports, err
+		}
+		if r.Size != e.Size || sum != e.SHA256 {
+			r.Status = FileModified
+			continue
+		}
+
+		if opts.CheckRemote && e.RemoteURL != "" && e.Type != AssetLinks && !e.HLS {
+			r.RemoteSize = remoteSize(ctx, opts.HTTPClient, e.RemoteURL)
+			if r.RemoteSize >= 0 && r.RemoteSize != e.Size {
+				r.Status = FileRemote
+			}
+		}
+	}
+
+	// then look for files missing from the manifest
+	err = filepath.Walk(courseDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(courseDir, p)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && rel == ArchiveDirName {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || isBookkeepingFile(rel) {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if !tracked[rel] {
+			reports = append(reports, &FileReport{Path: rel, Status: FileUntracked, Size: info.Size(), RemoteSize: -1})
+		}
+		return nil
+	})
+	return m, reports, err
+}
+
+// isBookkeepingFile reports whether the file belongs to the tool, rather than the backup
+func isBookkeepingFile(rel string) bool {
+	return rel == ManifestFileName ||
+		strings.HasSuffix(rel, ".tmp") ||
+		strings.HasSuffix(rel, ".tmp.resume")
+}
+
+// remoteSize returns the size of a remote file (using a HEAD request), or -1 if unknown
+func remoteSize(ctx context.Context, c *http.Client, url string) int64 {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return -1
+	}
+	res, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return -1
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return -1 // <- the URL probably expired
+	}
+	return res.ContentLength
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/viper"
+	"github.com/ushu/udemy-backup/client"
+)
+
+// Environment variables holding the credentials
+const (
+	IDEnv    = "UDEMY_ID"
+	TokenEnv = "UDEMY_TOKEN"
+)
+
+// EnsureCredentials returns the Udemy client ID and access token, looking in order into:
+// the --id and --token flags, the config file, the UDEMY_ID and UDEMY_TOKEN environment
+// variables, and finally asking the user to log in.
+func EnsureCredentials() (id string, token string, err error) {
+	// flags & config file (through viper)
+	id = viper.GetString("id")
+	token = viper.GetString("token")
+
+	// environment
+	if id == "" {
+		id = os.Getenv(IDEnv)
+	}
+	if token == "" {
+		token = os.Getenv(TokenEnv)
+	}
+	if id != "" && token != "" {
+		return
+	}
+
+	// log the user in
+	email, password, err := AskCredentials()
+	if err != nil {
+		return "", "", err
+	}
+	cred, err := client.New().Login(context.Background(), email, password)
+	if err != nil {
+		return "", "", err
+	}
+	return cred.ID, cred.AccessToken, nil
+}
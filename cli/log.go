@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// Output streams, for regular messages and errors
+var (
+	Stdout io.Writer = os.Stdout
+	Stderr io.Writer = os.Stderr
+)
+
+// Quiet reports whether the regular messages are disabled (by the --quiet flag)
+func Quiet() bool {
+	return viper.GetBool("quiet")
+}
+
+// Log prints a message on stdout, unless in quiet mode
+func Log(a ...interface{}) {
+	if !Quiet() {
+		fmt.Fprintln(Stdout, a...)
+	}
+}
+
+// Logf prints a formatted message on stdout, unless in quiet mode
+func Logf(format string, a ...interface{}) {
+	if !Quiet() {
+		fmt.Fprintf(Stdout, format, a...)
+	}
+}
+
+// Logerr prints an error message on stderr
+func Logerr(a ...interface{}) {
+	fmt.Fprintln(Stderr, a...)
+}
+
+// Logerrf prints a formatted error message on stderr
+func Logerrf(format string, a ...interface{}) {
+	fmt.Fprintf(Stderr, format, a...)
+}
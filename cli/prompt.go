@@ -1,4 +1,4 @@
-package main
+package cli
 
 import (
 	"errors"
@@ -11,8 +11,8 @@ import (
 // inspired from taken verbatim from http://www.golangprograms.com/regular-expression-to-validate-email-address.html
 var emailRegexp = regexp.MustCompile("(?i)^[A-Z0-9._%+-]+@[A-Z0-9.-]+\\.[A-Z]{2,8}$")
 
-// selectCourse allows to select a course among a previously-downloaded list
-func selectCourse(courses []*client.Course) (*client.Course, error) {
+// SelectCourse allows to select a course among a previously-downloaded list
+func SelectCourse(courses []*client.Course) (*client.Course, error) {
 	templates := &promptui.SelectTemplates{
 		Label:    "{{ . }}?",
 		Active:   "🤓 {{ .Title | cyan }} ({{ .ID | red }})",
@@ -37,7 +37,8 @@ func selectCourse(courses []*client.Course) (*client.Course, error) {
 	return courses[i], nil
 }
 
-func askCredentials() (email string, password string, err error) {
+// AskCredentials prompts the user for its Udemy email and password
+func AskCredentials() (email string, password string, err error) {
 	prompt := promptui.Prompt{
 		Label:    "Email",
 		Validate: isEmail,
@@ -22,6 +22,8 @@ import (
 type Client struct {
 	HTTPClient  *http.Client
 	Credentials Credentials
+	BaseURL     string // root URL of the Udemy API
+	UserAgent   string // User-Agent header sent with API calls
 }
 
 type Credentials struct {
@@ -38,28 +40,63 @@ type PaginationOptions struct {
 const LoginFormURL = "https://www.udemy.com/join/login-popup/?display_type=popup&response_type=json"
 
 const BaseURL = "https://www.udemy.com/api-2.0"
+
+// WebURL is the root URL of the Udemy website, to which the course URLs are relative
+const WebURL = "https://www.udemy.com"
 const (
 	UserPath      = "users/me"
 	MyCoursesPath = "users/me/subscribed-courses"
 	CoursesPath   = "courses"
+	QuizzesPath   = "quizzes"
 	Timeout       = time.Second * 600
 )
 
-func New() *Client {
-	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
-	return &Client{
+// taken from https://github.com/riazXrazor/udemy-dl/blob/master/lib/core.js
+const DefaultUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.10; rv:39.0) Gecko/20100101 Firefox/39.0"
+
+// New creates a client for the Udemy API, configured by the given options, as in:
+//
+//	c := client.New(client.WithCredentials(id, token), client.WithTimeout(time.Minute))
+func New(opts ...Option) *Client {
+	c := &Client{
 		HTTPClient: &http.Client{
-			Jar:     jar,
+			Jar:     newCookieJar(),
 			Timeout: Timeout,
 			Transport: &http.Transport{
 				DisableKeepAlives: true,
 			},
 		},
+		BaseURL:   BaseURL,
+		UserAgent: DefaultUserAgent,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// newCookieJar returns the cookie jar of the HTTP clients, where Login finds the credentials
+func newCookieJar() http.CookieJar {
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return jar
+}
+
+// loginURL returns the URL of the login form, on the host of c.BaseURL
+func (c *Client) loginURL() string {
+	base, err := url.Parse(c.BaseURL)
+	if err != nil || base.Host == "" {
+		return LoginFormURL
 	}
+	u, _ := url.Parse(LoginFormURL)
+	u.Scheme, u.Host = base.Scheme, base.Host
+	return u.String()
 }
 
 func (c *Client) Login(ctx context.Context, email, password string) (Credentials, error) {
 	var cred Credentials
+	if c.HTTPClient == nil || c.HTTPClient.Jar == nil {
+		return cred, errors.New("login requires an HTTP client with a cookie jar")
+	}
 
 	// load the form
 	token, err := c.getCSRFToken(ctx)
@@ -68,7 +105,7 @@ func (c *Client) Login(ctx context.Context, email, password string) (Credentials
 	time.Sleep(1 * time.Second)
 
 	// prepare the request
-	u := LoginFormURL
+	u := c.loginURL()
 	params := url.Values{
 		"email":               {email},
 		"password":            {password},
@@ -91,7 +128,7 @@ func (c *Client) Login(ctx context.Context, email, password string) (Credentials
 		return cred, err
 	}
 
-	loginURL, _ := url.Parse(LoginFormURL)
+	loginURL, _ := url.Parse(u)
 	for _, cookie := range c.HTTPClient.Jar.Cookies(loginURL) {
 		if cookie.Name == "access_token" {
 			cred.AccessToken = cookie.Value
@@ -114,12 +151,15 @@ func (c *Client) Login(ctx context.Context, email, password string) (Credentials
 
 func (c *Client) GetUser(ctx context.Context) (*User, error) {
 	var u *User
-	err := c.getJson(ctx, BaseURL+"/"+UserPath, &u)
+	err := c.getJson(ctx, c.BaseURL+"/"+UserPath, &u)
 	return u, err
 }
 
 func (c *Client) ListCourses(ctx context.Context, opt *PaginationOptions) (*Courses, error) {
-	u, _ := url.Parse(BaseURL)
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, err
+	}
 	u.Path = path.Join(u.Path, MyCoursesPath)
 	// add page info
 	q := u.Query()
@@ -135,27 +175,39 @@ func (c *Client) ListCourses(ctx context.Context, opt *PaginationOptions) (*Cour
 	u.RawQuery = q.Encode()
 
 	var cc *Courses
-	err := c.getJson(ctx, u.String(), &cc)
+	err = c.getJson(ctx, u.String(), &cc)
 	return cc, err
 }
 
 func (c *Client) GetCourse(ctx context.Context, ID int) (*Course, error) {
-	u, _ := url.Parse(BaseURL)
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, err
+	}
 	u.Path = path.Join(u.Path, MyCoursesPath, strconv.Itoa(ID))
+	q := u.Query()
+	q.Set("fields[course]", "@min,title,published_title,headline,description,visible_instructors,what_you_will_learn_data,requirements_data,locale,last_update_date,image_240x135,image_480x270,image_750x422")
+	q.Set("fields[user]", "@min,title,name,display_name,url,job_title,image_100x100")
+	u.RawQuery = q.Encode()
 
 	var course *Course
-	err := c.getJson(ctx, u.String(), &course)
+	err = c.getJson(ctx, u.String(), &course)
 	return course, err
 }
 
 func (c *Client) LoadCurriculum(ctx context.Context, courseID int, opt *PaginationOptions) (*Curriculum, error) {
-	u, _ := url.Parse(BaseURL)
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, err
+	}
 	u.Path = path.Join(u.Path, CoursesPath, strconv.Itoa(courseID), "cached-subscriber-curriculum-items")
 	q := u.Query()
-	q.Set("fields[asset]", "@min,download_urls,stream_urls,external_url,slide_urls,captions")
+	q.Set("fields[asset]", "@min,download_urls,stream_urls,external_url,slide_urls,captions,body,time_estimation")
 	q.Set("fields[lecture]", "@min,title,title_cleaned,asset,object_index,supplementary_assets")
-	q.Set("fields[caption]", "@min,file_name,locale,url")
+	q.Set("fields[caption]", "@min,file_name,locale,url,source,video_label")
 	q.Set("fields[chapter]", "@min,title,object_index")
+	q.Set("fields[quiz]", "@min,title,object_index,type,description,duration,pass_percent")
+	q.Set("fields[practice]", "@min,title,object_index,description")
 	if opt != nil {
 		if opt.Page > 1 {
 			q.Set("page", strconv.Itoa(opt.Page))
@@ -168,10 +220,34 @@ func (c *Client) LoadCurriculum(ctx context.Context, courseID int, opt *Paginati
 
 	// load curriculum as lectures
 	var l *Curriculum
-	err := c.getJson(ctx, u.String(), &l)
+	err = c.getJson(ctx, u.String(), &l)
 	return l, err
 }
 
+func (c *Client) LoadAssessments(ctx context.Context, quizID int, opt *PaginationOptions) (*Assessments, error) {
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, QuizzesPath, strconv.Itoa(quizID), "assessments")
+	q := u.Query()
+	q.Set("version", "1")
+	q.Set("fields[assessment]", "id,assessment_type,prompt,correct_response,section,question_plain")
+	if opt != nil {
+		if opt.Page > 1 {
+			q.Set("page", strconv.Itoa(opt.Page))
+		}
+		if opt.PageSize > 1 {
+			q.Set("page_size", strconv.Itoa(opt.PageSize))
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	var a *Assessments
+	err = c.getJson(ctx, u.String(), &a)
+	return a, err
+}
+
 // getJSON calls GET and unmarshals the response JSON body
 func (c *Client) getJson(ctx context.Context, url string, o interface{}) error {
 	res, err := c.GET(ctx, url)
@@ -189,19 +265,20 @@ func (c *Client) getJson(ctx context.Context, url string, o interface{}) error {
 }
 
 // GET sends a GET request to Udemy, adding a whole lot of headers in the process
-func (c *Client) GET(ctx context.Context, url string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func (c *Client) GET(ctx context.Context, rawurl string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", rawurl, nil)
 	if err != nil {
 		return nil, err
 	}
 	if ctx != nil {
 		req = req.WithContext(ctx)
 	}
-	// taken from https://github.com/riazXrazor/udemy-dl/blob/master/lib/core.js
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.10; rv:39.0) Gecko/20100101 Firefox/39.0")
+	req.Header.Set("User-Agent", c.UserAgent)
 	req.Header.Set("X-Requested-With", "XMLHttpRequest")
-	req.Header.Set("Host", "www.udemy.com")
-	req.Header.Set("Origin", "https://www.udemy.com")
+	if base, err := url.Parse(c.BaseURL); err == nil {
+		req.Header.Set("Host", base.Host)
+		req.Header.Set("Origin", base.Scheme+"://"+base.Host)
+	}
 	if c.Credentials.ID != "" {
 		req.Header.Set("X-Udemy-Client-Id", c.Credentials.ID)
 	}
@@ -218,7 +295,7 @@ func (c *Client) GET(ctx context.Context, url string) (*http.Response, error) {
 // Loads the login form and extracts the temporary CSRF token (used for login !)
 func (c *Client) getCSRFToken(ctx context.Context) (string, error) {
 	// load the HTML for the login form
-	req, _ := http.NewRequest("GET", LoginFormURL, nil)
+	req, _ := http.NewRequest("GET", c.loginURL(), nil)
 	// & add headers to avoid "robot detection"
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Accept", "text/html")
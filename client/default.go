@@ -23,3 +23,15 @@ func GetCourse(ID int) (*Course, error) {
 func LoadCurriculum(courseID int, opt *PaginationOptions) (*Curriculum, error) {
 	return DefaultClient.LoadCurriculum(context.Background(), courseID, opt)
 }
+
+func ListAllCourses() ([]*Course, error) {
+	return DefaultClient.ListAllCourses(context.Background())
+}
+
+func LoadFullCurriculum(courseID int) (CurriculumItems, error) {
+	return DefaultClient.LoadFullCurriculum(context.Background(), courseID)
+}
+
+func LoadAllAssessments(quizID int) ([]*Assessment, error) {
+	return DefaultClient.LoadAllAssessments(context.Background(), quizID)
+}
@@ -6,8 +6,12 @@ import (
 	"github.com/ushu/udemy-backup/client"
 )
 
-const DefaultPageSize = 1400
+// DefaultPageSize is kept for compatibility, see client.DefaultPageSize
+const DefaultPageSize = client.DefaultPageSize
 
+// Lister loads all the pages of the Udemy listings.
+//
+// Deprecated: the pagination helpers are now available on *client.Client.
 type Lister client.Client
 
 func New(c *client.Client) *Lister {
@@ -15,48 +19,9 @@ func New(c *client.Client) *Lister {
 }
 
 func (l *Lister) LoadFullCurriculum(ctx context.Context, courseID int) (client.CurriculumItems, error) {
-	var res client.CurriculumItems
-	opt := &client.PaginationOptions{
-		Page:     1,
-		PageSize: DefaultPageSize,
-	}
-	for {
-		// load page info
-		cur, err := (*client.Client)(l).LoadCurriculum(ctx, courseID, opt)
-		if err != nil {
-			return res, err
-		}
-		res = append(res, cur.Results...)
-
-		// last page ?
-		if cur.Next == "" {
-			break
-		}
-		opt.Page++
-	}
-
-	return res, nil
+	return (*client.Client)(l).LoadFullCurriculum(ctx, courseID)
 }
 
 func (l *Lister) ListAllCourses(ctx context.Context) ([]*client.Course, error) {
-	var cc []*client.Course
-	opt := &client.PaginationOptions{
-		Page:     1,
-		PageSize: DefaultPageSize,
-	}
-	for {
-		// load page info
-		res, err := (*client.Client)(l).ListCourses(ctx, opt)
-		if err != nil {
-			return cc, err
-		}
-		cc = append(cc, res.Results...)
-
-		// last page ?
-		if res.Next == "" {
-			break
-		}
-		opt.Page++
-	}
-	return cc, nil
+	return (*client.Client)(l).ListAllCourses(ctx)
 }
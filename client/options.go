@@ -0,0 +1,63 @@
+package client
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures a Client created with New
+type Option func(c *Client)
+
+// WithCredentials sets the Udemy client ID and access token used to call the API
+func WithCredentials(id, accessToken string) Option {
+	return func(c *Client) {
+		c.Credentials = Credentials{ID: id, AccessToken: accessToken}
+	}
+}
+
+// WithHTTPClient replaces the default HTTP client (nil keeps the default one).
+// A client without cookie jar is copied, and given a jar for Login.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		if hc == nil {
+			return
+		}
+		if hc.Jar == nil {
+			cp := *hc
+			cp.Jar = newCookieJar()
+			c.HTTPClient = &cp
+			return
+		}
+		c.HTTPClient = hc
+	}
+}
+
+// WithBaseURL sets the root URL of the API (for example for Udemy for Business accounts).
+// Login uses the login form of the same host.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with each API call
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.UserAgent = userAgent
+	}
+}
+
+// WithTimeout sets the timeout of the HTTP client.
+// It applies to a copy of the HTTP client set by a previous WithHTTPClient option, if any,
+// so that the other users of that client keep their timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		hc := http.Client{Jar: newCookieJar()}
+		if c.HTTPClient != nil {
+			hc = *c.HTTPClient
+		}
+		hc.Timeout = timeout
+		c.HTTPClient = &hc
+	}
+}
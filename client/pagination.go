@@ -0,0 +1,78 @@
+package client
+
+import "context"
+
+// DefaultPageSize is the page size used when loading all the pages of a listing
+const DefaultPageSize = 1400
+
+// ListAllCourses loads all the pages of subscribed courses
+func (c *Client) ListAllCourses(ctx context.Context) ([]*Course, error) {
+	var cc []*Course
+	opt := &PaginationOptions{
+		Page:     1,
+		PageSize: DefaultPageSize,
+	}
+	for {
+		// load page info
+		res, err := c.ListCourses(ctx, opt)
+		if err != nil {
+			return cc, err
+		}
+		cc = append(cc, res.Results...)
+
+		// last page ?
+		if res.Next == "" {
+			break
+		}
+		opt.Page++
+	}
+	return cc, nil
+}
+
+// LoadFullCurriculum loads all the pages of the curriculum of a course
+func (c *Client) LoadFullCurriculum(ctx context.Context, courseID int) (CurriculumItems, error) {
+	var res CurriculumItems
+	opt := &PaginationOptions{
+		Page:     1,
+		PageSize: DefaultPageSize,
+	}
+	for {
+		// load page info
+		cur, err := c.LoadCurriculum(ctx, courseID, opt)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, cur.Results...)
+
+		// last page ?
+		if cur.Next == "" {
+			break
+		}
+		opt.Page++
+	}
+	return res, nil
+}
+
+// LoadAllAssessments loads all the pages of questions of a quiz
+func (c *Client) LoadAllAssessments(ctx context.Context, quizID int) ([]*Assessment, error) {
+	var aa []*Assessment
+	opt := &PaginationOptions{
+		Page:     1,
+		PageSize: DefaultPageSize,
+	}
+	for {
+		// load page info
+		res, err := c.LoadAssessments(ctx, quizID, opt)
+		if err != nil {
+			return aa, err
+		}
+		aa = append(aa, res.Results...)
+
+		// last page ?
+		if res.Next == "" {
+			break
+		}
+		opt.Page++
+	}
+	return aa, nil
+}
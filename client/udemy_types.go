@@ -12,6 +12,8 @@ type User struct {
 	Name        string `json:"name"`
 	DisplayName string `json:"display_name"`
 	URL         string `json:"url"`
+	JobTitle    string `json:"job_title"`     // for instructors
+	Image       string `json:"image_100x100"` // for instructors
 }
 
 type Courses struct {
@@ -26,6 +28,23 @@ type Course struct {
 	Title          string `json:"title"`
 	URL            string `json:"url"`
 	PublishedTitle string `json:"published_title"`
+
+	// extended fields, loaded by GetCourse
+	Headline       string   `json:"headline"`
+	Description    string   `json:"description"` // HTML
+	Instructors    []*User  `json:"visible_instructors"`
+	Objectives     ItemList `json:"what_you_will_learn_data"`
+	Requirements   ItemList `json:"requirements_data"`
+	Locale         *Locale  `json:"locale"`
+	LastUpdateDate string   `json:"last_update_date"` // as "2006-01-02"
+	Image240x135   string   `json:"image_240x135"`
+	Image480x270   string   `json:"image_480x270"`
+	Image750x422   string   `json:"image_750x422"`
+}
+
+// ItemList is a list of texts (like the objectives or requirements of a course)
+type ItemList struct {
+	Items []string `json:"items"`
 }
 
 type PriceDetail struct {
@@ -52,6 +71,103 @@ type Lecture struct {
 	ObjectIndex         int      `json:"object_index"`
 }
 
+type Quiz struct {
+	Chapter     *Chapter `json:"-"`
+	ID          int      `json:"id"`
+	Title       string   `json:"title"`
+	Type        string   `json:"type"` // "simple-quiz", "practice-test" or "coding-exercise"
+	Description string   `json:"description"`
+	ObjectIndex int      `json:"object_index"`
+	Duration    int      `json:"duration"`     // in seconds, for practice tests
+	PassPercent int      `json:"pass_percent"` // for practice tests
+}
+
+// Practice is a practice activity (assignment) of the curriculum
+type Practice struct {
+	Chapter     *Chapter `json:"-"`
+	ID          int      `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	ObjectIndex int      `json:"object_index"`
+}
+
+type Assessments struct {
+	Count    int           `json:"count"`
+	Next     string        `json:"next"`
+	Previous string        `json:"previous"`
+	Results  []*Assessment `json:"results"`
+}
+
+// Assessment is a question of a quiz
+type Assessment struct {
+	ID              int      `json:"id"`
+	AssessmentType  string   `json:"assessment_type"` // "multiple-choice", "multi-select"...
+	Prompt          *Prompt  `json:"prompt"`
+	CorrectResponse []string `json:"correct_response"` // letters of the correct answers ("a", "b"...)
+	Section         string   `json:"section"`
+	QuestionPlain   string   `json:"question_plain"`
+}
+
+// Prompt holds the contents of a question (as HTML)
+type Prompt struct {
+	Question    string   `json:"question"`
+	Answers     []string `json:"answers"`
+	Feedbacks   []string `json:"feedbacks"`
+	Explanation string   `json:"explanation"`
+
+	// coding problems
+	Instructions  string      `json:"instructions"`
+	Hints         []string    `json:"hints"`
+	InitialFiles  []*CodeFile `json:"initial_files"`
+	SolutionFiles []*CodeFile `json:"solution_files"`
+	TestFiles     []*CodeFile `json:"test_files"`
+}
+
+// CodeFile is a source file of a coding exercise
+type CodeFile struct {
+	FileName string `json:"file_name"`
+	Content  string `json:"content"`
+}
+
+// CodingExercise is a coding problem of a "coding-exercise" quiz
+type CodingExercise struct {
+	ID            int
+	Title         string
+	Instructions  string // HTML
+	Hints         []string
+	StarterFiles  []*CodeFile
+	SolutionFiles []*CodeFile
+	TestFiles     []*CodeFile
+}
+
+// CodingExercise returns the coding exercise described by a "coding-problem" assessment,
+// or nil for other types of assessments
+func (a *Assessment) CodingExercise() *CodingExercise {
+	if a.AssessmentType != "coding-problem" || a.Prompt == nil {
+		return nil
+	}
+	return &CodingExercise{
+		ID:            a.ID,
+		Title:         a.QuestionPlain,
+		Instructions:  a.Prompt.Instructions,
+		Hints:         a.Prompt.Hints,
+		StarterFiles:  a.Prompt.InitialFiles,
+		SolutionFiles: a.Prompt.SolutionFiles,
+		TestFiles:     a.Prompt.TestFiles,
+	}
+}
+
+// IsCorrect reports whether the answer at index i is one of the correct responses
+func (a *Assessment) IsCorrect(i int) bool {
+	letter := string(rune('a' + i))
+	for _, r := range a.CorrectResponse {
+		if r == letter {
+			return true
+		}
+	}
+	return false
+}
+
 type Chapter struct {
 	ID          int    `json:"id"`
 	Title       string `json:"title"`
@@ -59,18 +175,48 @@ type Chapter struct {
 }
 
 type Asset struct {
-	ID           int           `json:"id"`
-	AssetType    string        `json:"asset_type"`
-	Title        string        `json:"title"`
-	ExternalURL  string        `json:"external_url"`
-	DownloadUrls *DownloadURLs `json:"download_urls"`
-	//SlideUrls    []interface{} `json:"slide_urls"`
-	StreamUrls *StreamURLs `json:"stream_urls"`
-	Captions   []*Caption  `json:"captions"`
+	ID             int           `json:"id"`
+	AssetType      string        `json:"asset_type"`
+	Title          string        `json:"title"`
+	ExternalURL    string        `json:"external_url"`
+	DownloadUrls   *DownloadURLs `json:"download_urls"`
+	SlideUrls      []*Slide      `json:"slide_urls"` // for presentations, in order
+	StreamUrls     *StreamURLs   `json:"stream_urls"`
+	Captions       []*Caption    `json:"captions"`
+	Body           string        `json:"body"`            // HTML contents of articles
+	TimeEstimation int           `json:"time_estimation"` // duration of the videos, in seconds
+}
+
+// Slide is a slide image of a presentation
+type Slide struct {
+	URL  string
+	Time float64 // position of the slide in the synced video, in seconds (-1 if unknown)
+}
+
+// UnmarshalJSON loads a slide either from a plain URL, or from an object with its
+// "url" and sync "time"
+func (s *Slide) UnmarshalJSON(data []byte) error {
+	s.Time = -1
+	if err := json.Unmarshal(data, &s.URL); err == nil {
+		return nil
+	}
+	var obj struct {
+		URL  string   `json:"url"`
+		Time *float64 `json:"time"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	s.URL = obj.URL
+	if obj.Time != nil {
+		s.Time = *obj.Time
+	}
+	return nil
 }
 
 type DownloadURLs struct {
 	Video []*Video `json:"Video"`
+	Audio []*Video `json:"Audio"`
 	File  []*File  `json:"File"`
 	Ebook []*File  `json:"E-Book"`
 }
@@ -104,6 +250,7 @@ type Caption struct {
 
 type Locale struct {
 	Locale string `json:"locale"`
+	Title  string `json:"title"` // name of the language
 }
 
 type Curriculum struct {
@@ -113,7 +260,7 @@ type Curriculum struct {
 	Results  CurriculumItems `json:"results"`
 }
 
-// CurriculumItem contains either *Chapter or *Lecture items
+// CurriculumItem contains either *Chapter, *Lecture, *Quiz or *Practice items
 type CurriculumItems []interface{}
 
 func (c *CurriculumItems) UnmarshalJSON(data []byte) error {
@@ -126,6 +273,10 @@ func (c *CurriculumItems) UnmarshalJSON(data []byte) error {
 		Asset               *Asset   `json:"asset"`
 		SupplementaryAssets []*Asset `json:"supplementary_assets"`
 		ObjectIndex         int      `json:"object_index"`
+		Type                string   `json:"type"`
+		Description         string   `json:"description"`
+		Duration            int      `json:"duration"`
+		PassPercent         int      `json:"pass_percent"`
 	}
 	if err := json.Unmarshal(data, &items); err != nil {
 		return err
@@ -151,10 +302,27 @@ func (c *CurriculumItems) UnmarshalJSON(data []byte) error {
 				SupplementaryAssets: i.SupplementaryAssets,
 				Chapter:             currentChapter,
 			})
-		} else if i.Class == "quiz" || i.Class == "practice" {
-			// ignore for now on
+		} else if i.Class == "quiz" {
+			*c = append(*c, &Quiz{
+				ID:          i.ID,
+				Title:       i.Title,
+				Type:        i.Type,
+				Description: i.Description,
+				ObjectIndex: i.ObjectIndex,
+				Duration:    i.Duration,
+				PassPercent: i.PassPercent,
+				Chapter:     currentChapter,
+			})
+		} else if i.Class == "practice" {
+			*c = append(*c, &Practice{
+				ID:          i.ID,
+				Title:       i.Title,
+				Description: i.Description,
+				ObjectIndex: i.ObjectIndex,
+				Chapter:     currentChapter,
+			})
 		} else {
-			return fmt.Errorf("unknown type for curriculum item at position %d: want \"chapter\" or \"lecture\", got %q", idx, i.Class)
+			return fmt.Errorf("unknown type for curriculum item at position %d: want \"chapter\", \"lecture\", \"quiz\" or \"practice\", got %q", idx, i.Class)
 		}
 	}
 	return nil
@@ -16,11 +16,25 @@ import (
 )
 
 var PreferredResolution int
+var ResolutionPolicy string
 var NumWorkers int
 var Dir string
 var Restart bool
 var All bool
 var Subtitles bool
+var SubtitleLocales []string
+var SubtitleFormat string
+var SubtitleAuto string
+var SubtitleStrip bool
+var SubtitlePlacement string
+var AudioOnly bool
+var PathProfile string
+var Layout string
+var CourseTemplate string
+var ChapterTemplate string
+var LectureTemplate string
+var Sync bool
+var Archive bool
 
 // backupCmd represents the backup command
 var backupCmd = &cobra.Command{
@@ -32,17 +46,45 @@ var backupCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(backupCmd)
-	backupCmd.PersistentFlags().IntVar(&PreferredResolution, "resolution", 0, "only download videos of the given resolution")
+	backupCmd.PersistentFlags().IntVar(&PreferredResolution, "resolution", 0, "preferred resolution of the videos (as 720)")
+	backupCmd.PersistentFlags().StringVar(&ResolutionPolicy, "resolution-policy", "closest", "selection of the resolution: exact, closest, at-most, lowest or highest")
 	backupCmd.PersistentFlags().IntVar(&NumWorkers, "concurrency", runtime.NumCPU(), "number of parallel downloads")
 	backupCmd.PersistentFlags().StringVar(&Dir, "dir", ".", "output directory for downloads")
-	backupCmd.PersistentFlags().BoolVar(&Restart, "restart", false, "skip download of existing files")
+	backupCmd.PersistentFlags().BoolVar(&Restart, "restart", false, "re-download existing files")
 	backupCmd.PersistentFlags().BoolVar(&All, "all", false, "backup all the subscribed courses for the account")
 	backupCmd.PersistentFlags().BoolVar(&Subtitles, "subtitles", false, "download subtitles (vtt) files")
+	backupCmd.PersistentFlags().StringSliceVar(&SubtitleLocales, "subtitle-locales", nil, "only download the subtitles of the given locales (as en,fr or en_US)")
+	backupCmd.PersistentFlags().StringVar(&SubtitleFormat, "subtitle-format", "vtt", "format of the subtitles: vtt, srt or both")
+	backupCmd.PersistentFlags().StringVar(&SubtitleAuto, "subtitle-auto", "prefer-human", "auto-generated subtitles: prefer-human, human-only or all")
+	backupCmd.PersistentFlags().BoolVar(&SubtitleStrip, "subtitle-strip", false, "remove the styling from the vtt subtitles")
+	backupCmd.PersistentFlags().StringVar(&SubtitlePlacement, "subtitle-placement", "folder", "where to write the subtitles: folder (lecture assets folder) or video (next to the video)")
+	backupCmd.PersistentFlags().BoolVar(&AudioOnly, "audio-only", false, "only download the audio of the lectures, tagged to be listened to as a podcast")
+	backupCmd.PersistentFlags().StringVar(&PathProfile, "path-profile", "posix", "file systems the file names must be valid on: posix, windows, exfat or ascii")
+	backupCmd.PersistentFlags().StringVar(&Layout, "layout", "default", "organization of the files: default, or show (chapters as seasons and lectures as episodes, for Plex or Jellyfin)")
+	backupCmd.PersistentFlags().StringVar(&CourseTemplate, "course-template", "", "template of the course directory names (as {{.Course.Title}})")
+	backupCmd.PersistentFlags().StringVar(&ChapterTemplate, "chapter-template", "", "template of the chapter directory names (as {{printf \"%02d\" .Chapter.ObjectIndex}} - {{.Chapter.Title}})")
+	backupCmd.PersistentFlags().StringVar(&LectureTemplate, "lecture-template", "", "template of the lecture file names")
+	backupCmd.PersistentFlags().BoolVar(&Sync, "sync", false, "move the files of renamed lectures instead of downloading them again")
+	backupCmd.PersistentFlags().BoolVar(&Archive, "archive", false, "with --sync, move the files of removed lectures into .archive/")
 	viper.BindPFlag("resolution", backupCmd.PersistentFlags().Lookup("resolution"))
+	viper.BindPFlag("resolution-policy", backupCmd.PersistentFlags().Lookup("resolution-policy"))
 	viper.BindPFlag("concurrency", backupCmd.PersistentFlags().Lookup("concurrency"))
 	viper.BindPFlag("dir", backupCmd.PersistentFlags().Lookup("dir"))
 	viper.BindPFlag("restart", backupCmd.PersistentFlags().Lookup("restart"))
 	viper.BindPFlag("subtitles", backupCmd.PersistentFlags().Lookup("subtitles"))
+	viper.BindPFlag("subtitle-locales", backupCmd.PersistentFlags().Lookup("subtitle-locales"))
+	viper.BindPFlag("subtitle-format", backupCmd.PersistentFlags().Lookup("subtitle-format"))
+	viper.BindPFlag("subtitle-auto", backupCmd.PersistentFlags().Lookup("subtitle-auto"))
+	viper.BindPFlag("subtitle-strip", backupCmd.PersistentFlags().Lookup("subtitle-strip"))
+	viper.BindPFlag("subtitle-placement", backupCmd.PersistentFlags().Lookup("subtitle-placement"))
+	viper.BindPFlag("audio-only", backupCmd.PersistentFlags().Lookup("audio-only"))
+	viper.BindPFlag("path-profile", backupCmd.PersistentFlags().Lookup("path-profile"))
+	viper.BindPFlag("layout", backupCmd.PersistentFlags().Lookup("layout"))
+	viper.BindPFlag("course-template", backupCmd.PersistentFlags().Lookup("course-template"))
+	viper.BindPFlag("chapter-template", backupCmd.PersistentFlags().Lookup("chapter-template"))
+	viper.BindPFlag("lecture-template", backupCmd.PersistentFlags().Lookup("lecture-template"))
+	viper.BindPFlag("sync", backupCmd.PersistentFlags().Lookup("sync"))
+	viper.BindPFlag("archive", backupCmd.PersistentFlags().Lookup("archive"))
 }
 
 func runBackup(cmd *cobra.Command, args []string) {
@@ -51,14 +93,23 @@ func runBackup(cmd *cobra.Command, args []string) {
 	// grab credentials
 	id, token, err := cli.EnsureCredentials()
 	if err != nil {
-		cli.Logerr("Failed to load credentials: %v\n", err)
+		cli.Logerrf("Failed to load credentials: %v\n", err)
 		os.Exit(1)
 	}
 
 	// we can now load the generic backup options
-	c := client.New(id, token)
-	cfg := config.New(ctx, c)
+	c := client.New(client.WithCredentials(id, token))
+	cfg, err := config.New(ctx, c)
+	if err != nil {
+		cli.Logerr("Invalid configuration:", err)
+		os.Exit(1)
+	}
 	ctx = config.NewContext(ctx, cfg)
+	ctx = backup.WithEventHandler(ctx, func(e backup.Event) {
+		if e.Type == backup.EventWarning {
+			cli.Logerrf("⚠️  %v\n", e.Err)
+		}
+	})
 
 	// and prepare the worker pool
 	workerPool := pool.New(cfg.NumWorkers)
@@ -66,13 +117,16 @@ func runBackup(cmd *cobra.Command, args []string) {
 	ctx = pool.NewContext(ctx, workerPool)
 
 	// here we start "enqueuing" the work on the pool
+	ok := true
+	enqueued := make(chan struct{})
 	go func() {
+		defer close(enqueued)
 		defer workerPool.Done()
 
 		if All {
-			backupAllCourses(ctx, c)
+			ok = backupAllCourses(ctx, c)
 		} else {
-			if len(args) > 1 {
+			if len(args) > 0 {
 				courseID, err := strconv.Atoi(args[0])
 				if err != nil {
 					cli.Logerr("COURSE_ID should be a number (integer)")
@@ -85,32 +139,41 @@ func runBackup(cmd *cobra.Command, args []string) {
 		}
 	}()
 
-	if err := workerPool.Start(ctx); err != nil {
+	err = workerPool.Start(ctx)
+	<-enqueued
+	if err != nil && ok {
 		cli.Logerr("Backup failed:", err)
+	}
+	if err != nil || !ok {
 		os.Exit(1)
 	}
 }
 
-func backupAllCourses(ctx context.Context, c *client.Client) {
+// backupAllCourses backs up all the courses, even when some of them fail (the errors are
+// logged), and reports whether all the backups succeeded
+func backupAllCourses(ctx context.Context, c *client.Client) bool {
 	// list all the course
-	courses, err := c.ListAllCourses()
+	courses, err := c.ListAllCourses(ctx)
 	if err != nil {
 		cli.Logerrf("Failed to list courses: %v\n", err)
 		os.Exit(1)
 	}
 	cli.Logf("⚙️  Found %d courses to backup\n", len(courses))
 
+	ok := true
 	for _, course := range courses {
 		cli.Log("⚙️  Starting backup for:", course.Title)
-		if err = backup.BackupCourse(ctx, course); err != nil {
-			os.Exit(1)
+		if _, err = backup.BackupCourse(ctx, course); err != nil {
+			cli.Logerrf("Backup of %s failed: %v\n", course.Title, err)
+			ok = false
 		}
 	}
+	return ok
 }
 
 func selectAndBackupCourse(ctx context.Context, c *client.Client) {
 	// list all the course
-	courses, err := c.ListAllCourses()
+	courses, err := c.ListAllCourses(ctx)
 	if err != nil {
 		cli.Logerrf("Failed to list courses: %v\n", err)
 		os.Exit(1)
@@ -123,20 +186,20 @@ func selectAndBackupCourse(ctx context.Context, c *client.Client) {
 		os.Exit(1)
 	}
 
-	if err = backup.BackupCourse(ctx, course); err != nil {
+	if _, err = backup.BackupCourse(ctx, course); err != nil {
 		cli.Logerr("Backup failed:", err)
 		os.Exit(1)
 	}
 }
 
 func backupCourse(ctx context.Context, c *client.Client, courseID int) {
-	course, err := c.GetCourse(courseID)
+	course, err := c.GetCourse(ctx, courseID)
 	if err != nil {
 		cli.Logerr("Could not load course info:", err)
 		os.Exit(1)
 	}
 
-	if err = backup.BackupCourse(ctx, course); err != nil {
+	if _, err = backup.BackupCourse(ctx, course); err != nil {
 		cli.Logerr("Backup failed:", err)
 		os.Exit(1)
 	}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/csv"
 	"os"
 	"strconv"
@@ -33,8 +34,8 @@ func list(cmd *cobra.Command, args []string) {
 	}
 
 	// and test connection to the remote server
-	c := client.New(id, token)
-	courses, err := c.ListAllCourses()
+	c := client.New(client.WithCredentials(id, token))
+	courses, err := c.ListAllCourses(context.Background())
 	if err != nil {
 		cli.Logerrf("Failed to list courses: %v\n", err)
 		os.Exit(1)
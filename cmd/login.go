@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -29,8 +30,8 @@ func login(cmd *cobra.Command, args []string) {
 	}
 
 	// and test connection to the remote server
-	c := client.New(id, token)
-	user, err := c.GetUser()
+	c := client.New(client.WithCredentials(id, token))
+	user, err := c.GetUser(context.Background())
 	if err != nil {
 		cli.Logerrf("Failed to user info: %v\n", err)
 		os.Exit(1)
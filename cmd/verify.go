@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/ushu/udemy-backup/backup"
+	"github.com/ushu/udemy-backup/backup/config"
+	"github.com/ushu/udemy-backup/cli"
+	"github.com/ushu/udemy-backup/client"
+)
+
+var Remote bool
+var Fix bool
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify COURSE_DIR...",
+	Short: "Verify the files of backed up courses",
+	Long:  `Checks the size and SHA-256 of all the files of a course directory against its manifest, and reports the missing, truncated and modified files.`,
+	Args:  cobra.MinimumNArgs(1),
+	Run:   verify,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.PersistentFlags().BoolVar(&Remote, "remote", false, "also compare the sizes with the remote files")
+	verifyCmd.PersistentFlags().BoolVar(&Fix, "fix", false, "download the broken files again")
+}
+
+func verify(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+
+	failed := false
+	for _, courseDir := range args {
+		broken, ok := verifyCourseDir(ctx, courseDir)
+		if !ok {
+			failed = true
+			continue
+		}
+		if len(broken) > 0 && Fix {
+			if err := fixCourseDir(ctx, courseDir, broken); err != nil {
+				cli.Logerrf("Could not fix %s: %v\n", courseDir, err)
+				failed = true
+			}
+		} else if len(broken) > 0 {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// verifyCourseDir prints the report for a course directory, and returns the broken files
+func verifyCourseDir(ctx context.Context, courseDir string) ([]*backup.FileReport, bool) {
+	opts := backup.VerifyOptions{CheckRemote: Remote}
+	m, reports, err := backup.Verify(ctx, courseDir, opts)
+	if err != nil {
+		cli.Logerrf("Could not verify %s: %v\n", courseDir, err)
+		return nil, false
+	}
+	if len(m.Entries) == 0 {
+		cli.Logerrf("No manifest found in %s\n", courseDir)
+		return nil, false
+	}
+
+	cli.Logf("🔎  %s (%d files)\n", m.CourseTitle, len(m.Entries))
+	var broken []*backup.FileReport
+	for _, r := range reports {
+		if r.Status == backup.FileOK {
+			continue
+		}
+		cli.Logf("| %-9s | %s\n", r.Status, r.Path)
+		if r.Broken() {
+			broken = append(broken, r)
+		}
+	}
+	if len(broken) == 0 {
+		cli.Log("✅  All files are valid")
+	} else {
+		cli.Logf("❌  %d broken files\n", len(broken))
+	}
+	return broken, true
+}
+
+// fixCourseDir removes the broken files, then runs a backup of the course to download them again
+func fixCourseDir(ctx context.Context, courseDir string, broken []*backup.FileReport) error {
+	m, err := backup.LoadManifest(courseDir)
+	if err != nil {
+		return err
+	}
+	for _, r := range broken {
+		p := filepath.Join(courseDir, filepath.FromSlash(r.Path))
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		m.Remove(r.Path)
+	}
+	if err = m.Save(courseDir); err != nil {
+		return err
+	}
+
+	// grab credentials
+	id, token, err := cli.EnsureCredentials()
+	if err != nil {
+		return err
+	}
+	c := client.New(client.WithCredentials(id, token))
+	course, err := c.GetCourse(ctx, m.CourseID)
+	if err != nil {
+		return err
+	}
+
+	// and run a backup of the course into the course directory, with the options of the
+	// backup (when recorded) so that the files get the same names
+	cfg, err := config.New(ctx, c)
+	if err != nil {
+		return err
+	}
+	if m.Options != nil {
+		m.Options.Apply(cfg)
+		if err = cfg.Validate(); err != nil {
+			return err
+		}
+	}
+	courseDir = filepath.Clean(courseDir)
+	cfg.RootDir = filepath.Dir(courseDir)
+	cfg.CourseDir = courseDir
+	ctx = config.NewContext(ctx, cfg)
+	cli.Logf("⚙️  Downloading %d files again\n", len(broken))
+	_, err = backup.BackupCourse(ctx, course)
+	return err
+}
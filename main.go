@@ -4,17 +4,18 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
 	"runtime"
-	"sync"
+	"strings"
 
 	"github.com/ushu/udemy-backup/backup"
+	"github.com/ushu/udemy-backup/backup/captions"
+	"github.com/ushu/udemy-backup/backup/config"
+	"github.com/ushu/udemy-backup/backup/resolution"
+	"github.com/ushu/udemy-backup/cli"
 	"github.com/ushu/udemy-backup/client"
-	"github.com/ushu/udemy-backup/client/lister"
 	pb "gopkg.in/cheggaaa/pb.v1"
 )
 
@@ -39,6 +40,20 @@ var (
 	output      string
 	clientID    string
 	accessToken string
+
+	preferredResolution int
+	resolutionPolicy    string
+
+	subtitles         bool
+	subtitleLocales   string
+	subtitleFormat    string
+	subtitleAuto      string
+	subtitleStrip     bool
+	subtitlePlacement string
+
+	audioOnly   bool
+	pathProfile string
+	layout      string
 )
 
 // Number of parallel workers
@@ -53,6 +68,17 @@ func init() {
 	flag.BoolVar(&showVersion, "v", false, "show version number")
 	flag.StringVar(&clientID, "c", "", "the client ID")
 	flag.StringVar(&accessToken, "t", "", "the Access Token")
+	flag.IntVar(&preferredResolution, "resolution", 0, "preferred resolution of the videos (as 720), the highest if not set")
+	flag.StringVar(&resolutionPolicy, "resolution-policy", "closest", "selection of the resolution: exact, closest, at-most (the preferred one or below), lowest or highest")
+	flag.BoolVar(&subtitles, "s", false, "download the subtitles of the videos")
+	flag.StringVar(&subtitleLocales, "subtitle-locales", "", "comma-separated list of the subtitle locales to download (as en,fr or en_US), all if empty")
+	flag.StringVar(&subtitleFormat, "subtitle-format", "vtt", "format of the subtitles: vtt, srt or both")
+	flag.StringVar(&subtitleAuto, "subtitle-auto", "prefer-human", "auto-generated subtitles: prefer-human, human-only or all")
+	flag.BoolVar(&subtitleStrip, "subtitle-strip", false, "remove the styling from the vtt subtitles")
+	flag.StringVar(&subtitlePlacement, "subtitle-placement", "folder", "where to write the subtitles: folder (lecture assets folder) or video (next to the video, for the players to find them)")
+	flag.BoolVar(&audioOnly, "audio", false, "only download the audio of the lectures, tagged to be listened to as a podcast")
+	flag.StringVar(&pathProfile, "path-profile", "posix", "file systems the file names must be valid on: posix, windows, exfat or ascii")
+	flag.StringVar(&layout, "layout", "default", "organization of the files: default, or show (chapters as seasons and lectures as episodes, for Plex or Jellyfin)")
 	flag.Usage = func() {
 		fmt.Print(usageDescription)
 		flag.PrintDefaults()
@@ -83,39 +109,43 @@ func main() {
 	}
 
 	// Connect to the Udemy backend
-	c := client.New()
+	var c *client.Client
 	if clientID == "" || accessToken == "" {
 		// log the user in
-		e, p, err := askCredentials()
+		e, p, err := cli.AskCredentials()
 		if err != nil {
 			log.Fatal(err)
 		}
+		c = client.New()
 		_, err = c.Login(ctx, e, p)
 		if err != nil {
 			log.Fatal(err)
 		}
 	} else {
-		c.Credentials.ID = clientID
-		c.Credentials.AccessToken = accessToken
+		c = client.New(client.WithCredentials(clientID, accessToken))
 	}
 
 	// list all the courses
-	l := lister.New(c)
-	courses, err := l.ListAllCourses(ctx)
+	courses, err := c.ListAllCourses(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// we're logged in !
 	if downloadAll {
+		failed := false
 		for _, course := range courses {
 			log.Printf("🚀 %s", course.Title)
 			if err = downloadCourse(ctx, c, course); err != nil {
-				log.Fatal(err)
+				log.Printf("❌ %s: %v", course.Title, err) // <- the other courses still get backed up
+				failed = true
 			}
 		}
+		if failed {
+			os.Exit(1)
+		}
 	} else {
-		course, err := selectCourse(courses)
+		course, err := cli.SelectCourse(courses)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -126,149 +156,62 @@ func main() {
 }
 
 func downloadCourse(ctx context.Context, client *client.Client, course *client.Course) error {
-	var err error
-
-	// list all the available course elements
-	b := backup.New(client, output, false)
-	allAssets, dirs, err := b.ListCourseAssets(ctx, course)
-	if err != nil {
+	cfg := &config.Config{
+		Client:              client,
+		RootDir:             output,
+		PreferredResolution: preferredResolution,
+		ResolutionPolicy:    resolution.Policy(resolutionPolicy),
+		NumWorkers:          concurrency,
+		Restart:             redownload,
+		LoadSubtitles:       subtitles,
+		Captions: captions.Options{
+			Locales:   splitList(subtitleLocales),
+			Format:    captions.Format(subtitleFormat),
+			Auto:      captions.AutoMode(subtitleAuto),
+			Strip:     subtitleStrip,
+			Placement: captions.Placement(subtitlePlacement),
+		},
+		AudioOnly:   audioOnly,
+		PathProfile: pathProfile,
+		Layout:      layout,
+	}
+	if err := cfg.Validate(); err != nil {
 		return err
 	}
+	ctx = config.NewContext(ctx, cfg)
 
-	// create all the required directories
-	for _, d := range dirs {
-		if !dirExists(d) {
-			if err = os.MkdirAll(d, 0755); err != nil {
-				log.Fatal(err)
-			}
-		}
-	}
-
-	// filter already-downloaded assets when "redownload" is selected
-	var assets []backup.Asset
-	if !redownload {
-		for _, a := range allAssets {
-			if !fileExists(a.LocalPath) {
-				assets = append(assets, a)
-			}
-		}
-	} else {
-		assets = allAssets
-	}
-
-	// create the "bar"
-	var bar *pb.ProgressBar
+	// report progress using a "bar"
 	if !quiet {
-		bar = pb.New(len(allAssets))
-		bar.Add(len(allAssets) - len(assets))
-		bar.Start()
-		defer bar.Update()
-	}
-
-	// start a cancelable context
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	// we use a pull of workers
-	chwork := make(chan backup.Asset)      // assets to process get enqueued here
-	cherr := make(chan error, concurrency) // download results
-
-	// start the workers
-	var wg sync.WaitGroup
-	wg.Add(concurrency)
-	for i := 0; i < concurrency; i++ {
-		go func() {
-			defer wg.Done()
-			for a := range chwork {
-				if a.RemoteURL != "" {
-					var err error
-				Retries:
-					for retry := 0; retry < 3; retry++ {
-						err = downloadURLToFile(ctx, client.HTTPClient, a.RemoteURL, a.LocalPath)
-						if err == nil {
-							break Retries
-						}
-					}
-					cherr <- err
-				} else if len(a.Contents) > 0 {
-					cherr <- ioutil.WriteFile(a.LocalPath, a.Contents, os.ModePerm)
-				}
-				if !quiet {
-					bar.Increment()
-				}
+		var bar *pb.ProgressBar
+		ctx = backup.WithEventHandler(ctx, func(e backup.Event) {
+			switch e.Type {
+			case backup.EventListed:
+				bar = pb.New(e.Total)
+				bar.Start()
+			case backup.EventSkipped, backup.EventDownloaded, backup.EventFailed:
+				bar.Increment()
+			case backup.EventWarning:
+				log.Printf("⚠️  %v", e.Err)
+			}
+		})
+		defer func() {
+			if bar != nil {
+				bar.Finish()
 			}
 		}()
 	}
 
-	// and the "pusher" goroutine
-	go func() {
-		// enqueue all assets (unless we cancel)
-		for _, a := range assets {
-			select {
-			case <-ctx.Done():
-				break
-			case chwork <- a:
-			}
-		}
-		// we close channels on "enqueing" side to avoid panics
-		close(chwork) // <- will stop the workers
-		wg.Wait()
-		close(cherr) // <- we close when we are sure there won't be a "write"
-	}()
-
-	// we wait for an error (if any)
-	for err := range cherr {
-		if err != nil {
-			return err // <- will cancel the context, then the "pusher", then the workers
-		}
-	}
-	return nil
+	_, err := backup.BackupCourse(ctx, course)
+	return err
 }
 
-func downloadURLToFile(ctx context.Context, c *http.Client, url, filePath string) error {
-	tmpPath := filePath + ".tmp"
-
-	// open file for writing
-	f, err := os.Create(tmpPath)
-	if err != nil {
-		return err
-	}
-
-	// connect to the backend to get the file
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		_ = f.Close()
-		return err
-	}
-	req = req.WithContext(ctx)
-	res, err := c.Do(req)
-	if err != nil {
-		_ = f.Close()
-		return err
-	}
-
-	// load all the data into the local file
-	_, err = io.Copy(f, res.Body)
-	_ = res.Body.Close()
-	if err != nil {
-		_ = f.Close()
-		return err
-	}
-
-	// finally move the temp file into the final place
-	err = f.Close()
-	if err != nil {
-		return err
+// splitList splits a comma-separated flag value
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
 	}
-	return os.Rename(tmpPath, filePath)
-}
-
-func fileExists(name string) bool {
-	_, err := os.Stat(name)
-	return !os.IsNotExist(err)
-}
-
-func dirExists(name string) bool {
-	s, err := os.Stat(name)
-	return !os.IsNotExist(err) && s.IsDir()
+	return list
 }